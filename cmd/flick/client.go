@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alejandro-bustamante/flick/internal/daemon"
+	"github.com/alejandro-bustamante/flick/internal/journal"
+	"github.com/alejandro-bustamante/flick/internal/quarantine"
+)
+
+const usage = `usage: flick [command]
+
+Without a command flick runs the daemon. The commands talk to a running daemon:
+
+  history                   list the recorded operations
+  undo <operation-id>       undo a single operation
+  undo-batch <batch-id>     undo every operation of a run
+  undo-since <when>         undo everything since a time (2006-01-02T15:04:05)
+                            or a duration ago (90m, 2h)
+  unmatched                 list the files that could not be organized
+  retry [path]              push one unmatched file, or all of them, through
+                            the organizer again
+`
+
+// runClient sends one command to the running daemon and prints the result.
+// It returns the exit code.
+func runClient(args []string) int {
+	if err := client(args); err != nil {
+		fmt.Fprintln(os.Stderr, "flick:", err)
+		return 1
+	}
+	return 0
+}
+
+func client(args []string) error {
+	command, args := args[0], args[1:]
+	switch command {
+	case "history":
+		var ops []journal.Operation
+		if err := daemon.Send(daemon.Request{Command: daemon.CmdJournalList}, &ops); err != nil {
+			return err
+		}
+		for _, op := range ops {
+			undone := ""
+			if op.UndoneAt != nil {
+				undone = " (undone)"
+			}
+			fmt.Printf("%s  %s  batch %s  %s -> %s%s\n",
+				op.ID, op.Timestamp.Format(time.DateTime), op.BatchID, op.Source, op.Destination, undone)
+		}
+		return nil
+
+	case "undo", "undo-batch":
+		if len(args) != 1 {
+			return fmt.Errorf("%s needs an ID\n\n%s", command, usage)
+		}
+		cmd := daemon.CmdJournalUndo
+		if command == "undo-batch" {
+			cmd = daemon.CmdJournalUndoBatch
+		}
+		return daemon.Send(daemon.Request{Command: cmd, ID: args[0]}, nil)
+
+	case "undo-since":
+		if len(args) != 1 {
+			return fmt.Errorf("undo-since needs a time or a duration\n\n%s", usage)
+		}
+		since, err := parseSince(args[0])
+		if err != nil {
+			return err
+		}
+		return daemon.Send(daemon.Request{Command: daemon.CmdJournalUndoSince, Since: since}, nil)
+
+	case "unmatched":
+		var records []quarantine.Record
+		if err := daemon.Send(daemon.Request{Command: daemon.CmdQuarantineList}, &records); err != nil {
+			return err
+		}
+		for _, record := range records {
+			fmt.Printf("%s  %s  attempt %d  %s", record.Time.Format(time.DateTime), record.Reason, record.Attempts, record.Path)
+			if record.Error != "" {
+				fmt.Printf(" (%s)", record.Error)
+			}
+			fmt.Println()
+		}
+		return nil
+
+	case "retry":
+		if len(args) > 1 {
+			return fmt.Errorf("retry takes at most one path\n\n%s", usage)
+		}
+		req := daemon.Request{Command: daemon.CmdQuarantineRetry}
+		if len(args) == 1 {
+			// Records hold absolute paths
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			req.Path = path
+		}
+		var retried int
+		if err := daemon.Send(req, &retried); err != nil {
+			return err
+		}
+		fmt.Printf("Retried %d file(s)\n", retried)
+		return nil
+
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
+
+// parseSince reads a point in time, either absolute in local time or a duration ago.
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", time.DateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a time nor a duration", value)
+}
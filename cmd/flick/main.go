@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	config "github.com/alejandro-bustamante/flick/internal/config"
 	"github.com/alejandro-bustamante/flick/internal/core"
+	"github.com/alejandro-bustamante/flick/internal/core/conflict"
 	finder "github.com/alejandro-bustamante/flick/internal/core/finder"
+	"github.com/alejandro-bustamante/flick/internal/core/naming"
 	parser "github.com/alejandro-bustamante/flick/internal/core/parser"
+	"github.com/alejandro-bustamante/flick/internal/core/transfer"
 	"github.com/alejandro-bustamante/flick/internal/daemon"
+	"github.com/alejandro-bustamante/flick/internal/journal"
+	"github.com/alejandro-bustamante/flick/internal/quarantine"
+	"github.com/alejandro-bustamante/flick/internal/review"
 	"github.com/alejandro-bustamante/flick/internal/utils"
 	"github.com/alejandro-bustamante/flick/internal/watcher"
 )
 
 func main() {
+	// With a command flick is only a client of the running daemon
+	if len(os.Args) > 1 {
+		os.Exit(runClient(os.Args[1:]))
+	}
+
 	// --- Load config ---
 	data, err := config.LoadData("/home/alejandro/nvme/Repositorios/Developer/flick/patterns.toml")
 	if err != nil {
@@ -37,34 +53,102 @@ func main() {
 
 	f := finder.NewTMDBFinder(sttgs.Secrets.TMDB_API_Key, p)
 
-	// 1. Create Watcher config
-	watcherConfig := watcher.WatcherConfig{
-		Path:           sttgs.Directories.Watch,
-		StabilityDelay: 2 * time.Second,
-		Recursive:      true,
+	// 1. Create a watcher per profile. Profiles were already validated by LoadSettings
+	var profiles []*core.Profile
+	for _, prof := range sttgs.Profiles {
+		watcherConfig := watcher.WatcherConfig{
+			Path:           prof.Watch,
+			StabilityDelay: 2 * time.Second,
+			Recursive:      true,
+			RescanInterval: time.Duration(sttgs.Watcher.RescanInterval) * time.Second,
+			Backend:        watcher.BackendKind(prof.Backend),
+			PollInterval:   time.Duration(sttgs.Watcher.PollInterval) * time.Second,
+			MaxPending:     sttgs.Watcher.MaxPending,
+			GroupByFolder:  sttgs.Watcher.GroupByFolder,
+			CheckWriters:   sttgs.Watcher.CheckWriters,
+			// Files still waiting to become stable are picked up again after a restart
+			StateFile: filepath.Join(config.DataDir(sttgs), "pending-"+prof.Name+".json"),
+			Filter: watcher.Filter{
+				Extensions:   data.Filter.Extensions,
+				Include:      data.Filter.Include,
+				Exclude:      data.Filter.Exclude,
+				MinSize:      data.Filter.MinSize,
+				IgnoreHidden: !data.Filter.AllowHidden,
+				IgnoreWords:  data.Filter.IgnoreWords,
+			},
+		}
+
+		// 2. Create the Watcher
+		folderWatcher, err := watcher.NewWatcher(watcherConfig)
+		if err != nil {
+			log.Fatalf("Error al crear el watcher de %s: %v", prof.Name, err)
+		}
+
+		movieTemplate, _ := naming.Parse(prof.MovieTemplate)
+		seriesTemplate, _ := naming.Parse(prof.SeriesTemplate)
+		profiles = append(profiles, &core.Profile{
+			Name:           prof.Name,
+			Watcher:        folderWatcher,
+			MoviesDir:      prof.Movies,
+			SeriesDir:      prof.Series,
+			MovieTemplate:  movieTemplate,
+			SeriesTemplate: seriesTemplate,
+			TransferMode:   transfer.Mode(prof.TransferMode),
+		})
 	}
 
-	// 2. Create the Watcher
-	folderWatcher, err := watcher.NewWatcher(watcherConfig)
+	// 3. Open the operation journal used for rollbacks and the review queue
+	opJournal, err := journal.Open(filepath.Join(config.DataDir(sttgs), "journal.jsonl"))
 	if err != nil {
-		log.Fatalf("Error al crear el watcher: %v", err)
+		log.Fatalf("Error al abrir el journal: %v", err)
 	}
+	defer opJournal.Close()
 
-	// 3. Create the Organizer, passing the watcher to it
-	organizer := core.NewOrganizer(p, f, folderWatcher, sttgs.Directories.Movies, sttgs.Directories.Series)
+	reviewQueue, err := review.Open(filepath.Join(config.DataDir(sttgs), "review.json"))
+	if err != nil {
+		log.Fatalf("Error al abrir la cola de revisión: %v", err)
+	}
 
-	// 4. Start the Organizer's main logic
-	organizer.Run()
+	// 4. Create the Organizer, passing the profiles and their watchers to it
+	organizerConfig := core.OrganizerConfig{
+		ReviewDir:       sttgs.Directories.Review,
+		ConflictPolicy:  conflict.Policy(sttgs.Organizer.OnConflict),
+		MinAccuracy:     sttgs.Organizer.MinAccuracy,
+		Workers:         sttgs.Organizer.Workers,
+		VerifyChecksums: sttgs.Organizer.VerifyChecksums,
+		VideoExtensions: data.Filter.Extensions,
+	}
+	var unmatched *quarantine.Quarantine
+	if sttgs.Directories.Unmatched != "" {
+		unmatched = quarantine.New(sttgs.Directories.Unmatched)
+	}
+	organizer := core.NewOrganizer(p, f, profiles, opJournal, reviewQueue, unmatched, organizerConfig)
+	// Scans must not queue again what the organizer already dealt with
+	for _, profile := range profiles {
+		profile.Watcher.Config.Skip = organizer.Handled
+	}
+
+	// Cancelled on Ctrl+C (SIGINT) or SIGTERM, shuts everything down
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 5. Start the Organizer's main logic
+	if err := organizer.Run(ctx); err != nil {
+		log.Fatalf("Error al iniciar el organizer: %v", err)
+	}
 
 	// --- Start the daemon (blocking function) ---
-	flickDaemon, err := daemon.NewDaemon()
+	flickDaemon, err := daemon.NewDaemon(organizer)
 	if err != nil {
 		log.Fatalf("No se pudo iniciar el daemon: %v", err)
 	}
 
 	// This blocks the main goroutine, while the Organizer
 	// and Watcher run in their own goroutines.
-	flickDaemon.Start()
+	flickDaemon.Start(ctx)
+
+	// Let files already being processed finish before exiting
+	organizer.Wait(time.Duration(sttgs.Organizer.ShutdownTimeout) * time.Second)
 
 	log.Println("Flick se ha detenido.")
 }
@@ -1,9 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/alejandro-bustamante/flick/internal/core/conflict"
+	"github.com/alejandro-bustamante/flick/internal/core/naming"
+	"github.com/alejandro-bustamante/flick/internal/core/transfer"
 	"github.com/alejandro-bustamante/flick/internal/models"
+	"github.com/alejandro-bustamante/flick/internal/watcher"
 	"github.com/pelletier/go-toml/v2"
 )
 
@@ -18,6 +26,28 @@ func LoadData(path string) (*models.Config, error) {
 	if err := toml.Unmarshal(patterns, &cfg); err != nil {
 		return nil, err
 	}
+
+	if len(cfg.Filter.Extensions) == 0 {
+		cfg.Filter.Extensions = slices.Clone(watcher.DefaultVideoExtensions)
+	}
+	for i, ext := range cfg.Filter.Extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		cfg.Filter.Extensions[i] = ext
+	}
+	if cfg.Filter.IgnoreWords == nil {
+		cfg.Filter.IgnoreWords = slices.Clone(watcher.DefaultIgnoreWords)
+	}
+	for _, glob := range slices.Concat(cfg.Filter.Include, cfg.Filter.Exclude) {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid filter glob %q: %v", glob, err)
+		}
+	}
+	if cfg.Filter.MinSize < 0 {
+		return nil, fmt.Errorf("min_size can't be negative, got %d", cfg.Filter.MinSize)
+	}
 	return &cfg, nil
 }
 
@@ -31,5 +61,126 @@ func LoadSettings(path string) (*models.UserSettings, error) {
 	if err := toml.Unmarshal(settings, &cfg); err != nil {
 		return nil, err
 	}
+
+	if cfg.Naming.Movie == "" {
+		cfg.Naming.Movie = naming.DefaultMovieTemplate
+	}
+	if cfg.Naming.Series == "" {
+		cfg.Naming.Series = naming.DefaultSeriesTemplate
+	}
+	if _, err := naming.Parse(cfg.Naming.Movie); err != nil {
+		return nil, fmt.Errorf("invalid movie naming template: %v", err)
+	}
+	if _, err := naming.Parse(cfg.Naming.Series); err != nil {
+		return nil, fmt.Errorf("invalid series naming template: %v", err)
+	}
+
+	mode, err := transfer.ParseMode(cfg.Organizer.TransferMode)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Organizer.TransferMode = string(mode)
+
+	policy, err := conflict.ParsePolicy(cfg.Organizer.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Organizer.OnConflict = string(policy)
+
+	backend, err := watcher.ParseBackendKind(cfg.Watcher.Backend)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Watcher.Backend = string(backend)
+	if cfg.Watcher.PollInterval <= 0 {
+		cfg.Watcher.PollInterval = 5
+	}
+
+	if cfg.Organizer.Workers <= 0 {
+		cfg.Organizer.Workers = 4
+	}
+	if cfg.Organizer.ShutdownTimeout <= 0 {
+		cfg.Organizer.ShutdownTimeout = 30
+	}
+
+	if cfg.Organizer.MinAccuracy < 0 || cfg.Organizer.MinAccuracy > 5 {
+		return nil, fmt.Errorf("min_accuracy must be between 0 and 5, got %d", cfg.Organizer.MinAccuracy)
+	}
+
+	if err := resolveProfiles(&cfg); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
+
+// resolveProfiles fills every profile with the global values it doesn't
+// override and validates it. Without profiles, [directories] becomes one.
+func resolveProfiles(cfg *models.UserSettings) error {
+	if len(cfg.Profiles) == 0 && cfg.Directories.Watch != "" {
+		cfg.Profiles = []models.Profile{{Name: "default", Watch: cfg.Directories.Watch}}
+	}
+	if len(cfg.Profiles) == 0 {
+		return fmt.Errorf("no watch folder configured, set directories.watch or add a [[profiles]] entry")
+	}
+
+	names := make(map[string]bool)
+	for i := range cfg.Profiles {
+		profile := &cfg.Profiles[i]
+		if profile.Watch == "" {
+			return fmt.Errorf("profile %d has no watch folder", i+1)
+		}
+		if profile.Name == "" {
+			profile.Name = filepath.Base(profile.Watch)
+		}
+		if names[profile.Name] {
+			return fmt.Errorf("duplicate profile name %q", profile.Name)
+		}
+		names[profile.Name] = true
+
+		if profile.Movies == "" {
+			profile.Movies = cfg.Directories.Movies
+		}
+		if profile.Series == "" {
+			profile.Series = cfg.Directories.Series
+		}
+		if profile.MovieTemplate == "" {
+			profile.MovieTemplate = cfg.Naming.Movie
+		}
+		if profile.SeriesTemplate == "" {
+			profile.SeriesTemplate = cfg.Naming.Series
+		}
+		if profile.TransferMode == "" {
+			profile.TransferMode = cfg.Organizer.TransferMode
+		}
+		if profile.Backend == "" {
+			profile.Backend = cfg.Watcher.Backend
+		}
+
+		if _, err := naming.Parse(profile.MovieTemplate); err != nil {
+			return fmt.Errorf("profile %s: invalid movie naming template: %v", profile.Name, err)
+		}
+		if _, err := naming.Parse(profile.SeriesTemplate); err != nil {
+			return fmt.Errorf("profile %s: invalid series naming template: %v", profile.Name, err)
+		}
+		if _, err := transfer.ParseMode(profile.TransferMode); err != nil {
+			return fmt.Errorf("profile %s: %v", profile.Name, err)
+		}
+		if _, err := watcher.ParseBackendKind(profile.Backend); err != nil {
+			return fmt.Errorf("profile %s: %v", profile.Name, err)
+		}
+	}
+	return nil
+}
+
+// DataDir returns the directory where flick keeps its state (journal, queues).
+func DataDir(settings *models.UserSettings) string {
+	if settings.Directories.Data != "" {
+		return settings.Directories.Data
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "flick")
+	}
+	return filepath.Join(home, ".local", "share", "flick")
+}
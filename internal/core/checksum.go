@@ -0,0 +1,28 @@
+package core
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+)
+
+// verifyCRC32 reads the whole file and compares its CRC32 with expected, in hex.
+// A mismatch means a broken or incomplete download.
+func verifyCRC32(filePath, expected string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIO, err)
+	}
+	defer file.Close()
+
+	hash := crc32.NewIEEE()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("%w: error reading %s: %v", ErrIO, filePath, err)
+	}
+	if actual := fmt.Sprintf("%08X", hash.Sum32()); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w: %s is %s, its name says %s", ErrChecksum, filePath, actual, expected)
+	}
+	return nil
+}
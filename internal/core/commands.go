@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/alejandro-bustamante/flick/internal/daemon"
+	"github.com/alejandro-bustamante/flick/internal/quarantine"
+)
+
+// Handle executes the commands the daemon receives from the TUI.
+func (o *Organizer) Handle(ctx context.Context, req daemon.Request) daemon.Response {
+	switch req.Command {
+	case daemon.CmdReviewList:
+		return daemon.OK(o.review.List())
+
+	case daemon.CmdReviewApprove:
+		if err := o.Approve(req.ID); err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(nil)
+
+	case daemon.CmdReviewRematch:
+		if err := o.Rematch(ctx, req.ID, req.TMDBID); err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(nil)
+
+	case daemon.CmdReviewReject:
+		if err := o.Reject(req.ID); err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(nil)
+
+	case daemon.CmdQuarantineList:
+		if o.quarantine == nil {
+			return daemon.OK(nil)
+		}
+		records, err := o.quarantine.List()
+		if err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(records)
+
+	case daemon.CmdQuarantineRetry:
+		retried, err := o.Retry(ctx, req.Path)
+		if err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(retried)
+
+	case daemon.CmdJournalList:
+		return daemon.OK(o.journal.Operations())
+
+	case daemon.CmdJournalUndo:
+		if err := o.journal.Undo(req.ID); err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(nil)
+
+	case daemon.CmdJournalUndoBatch:
+		if req.ID == "" {
+			return daemon.Errorf("a batch ID is required")
+		}
+		if err := o.journal.UndoBatch(req.ID); err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(nil)
+
+	case daemon.CmdJournalUndoSince:
+		if req.Since.IsZero() {
+			return daemon.Errorf("a start time is required")
+		}
+		if err := o.journal.UndoSince(req.Since); err != nil {
+			return daemon.Errorf("%v", err)
+		}
+		return daemon.OK(nil)
+
+	default:
+		return daemon.Errorf("unknown command %q", req.Command)
+	}
+}
+
+// Approve organizes a queued file using the candidate match as is. If it can't
+// be placed, e.g. ErrConflictSkipped, the item stays queued and the error says why.
+func (o *Organizer) Approve(id string) error {
+	item, ok := o.review.Get(id)
+	if !ok {
+		return fmt.Errorf("review item %s not found", id)
+	}
+
+	if err := o.place(item.Path, &item.Candidate, o.profileFor(item.OriginalPath)); err != nil {
+		return err
+	}
+	log.Printf("Review item %s approved: %s", id, item.Candidate.Title)
+	return o.review.Remove(id)
+}
+
+// Rematch organizes a queued file using a TMDb ID chosen by the user.
+func (o *Organizer) Rematch(ctx context.Context, id string, tmdbID int) error {
+	item, ok := o.review.Get(id)
+	if !ok {
+		return fmt.Errorf("review item %s not found", id)
+	}
+	if tmdbID <= 0 {
+		return fmt.Errorf("a TMDb ID is required to re-match")
+	}
+
+	mediaInfo, err := o.finder.GetMediaInfoByID(ctx, item.Candidate, tmdbID)
+	if err != nil {
+		return err
+	}
+	// Chosen by hand, as certain as it gets
+	mediaInfo.Accuracy = 5
+
+	if err := o.place(item.Path, mediaInfo, o.profileFor(item.OriginalPath)); err != nil {
+		return err
+	}
+	log.Printf("Review item %s re-matched to %s (TMDb %d)", id, mediaInfo.Title, tmdbID)
+	return o.review.Remove(id)
+}
+
+// Reject drops a queued item. The file goes to the unmatched folder if there
+// is one, otherwise it is left where it is.
+func (o *Organizer) Reject(id string) error {
+	item, ok := o.review.Get(id)
+	if !ok {
+		return fmt.Errorf("review item %s not found", id)
+	}
+	if err := o.review.Remove(id); err != nil {
+		return err
+	}
+	log.Printf("Review item %s rejected", id)
+
+	cause := fmt.Errorf("best match %q with accuracy %d was rejected", item.Candidate.Title, item.Candidate.Accuracy)
+	o.quarantineFile(item.Path, quarantine.ReasonLowAccuracy, cause, o.profileFor(item.OriginalPath))
+	return nil
+}
+
+// Retry pushes quarantined files back through the pipeline. An empty path
+// retries everything. Files that fail again go back to quarantine.
+func (o *Organizer) Retry(ctx context.Context, path string) (int, error) {
+	if o.quarantine == nil {
+		return 0, fmt.Errorf("no unmatched folder configured")
+	}
+	records, err := o.quarantine.List()
+	if err != nil {
+		return 0, err
+	}
+
+	retried := 0
+	for _, record := range records {
+		if ctx.Err() != nil {
+			break
+		}
+		if path != "" && record.Path != path {
+			continue
+		}
+		log.Printf("Retrying quarantined file %s (%s)", record.Path, record.Reason)
+		retried++
+		// On failure organize has already updated the record with the new reason.
+		// Parsed and routed by where the file was first found, its folders say
+		// more than the quarantine folder
+		if err := o.organize(ctx, record.Path, record.OriginalPath, o.profileFor(record.OriginalPath)); err == nil {
+			if err := o.quarantine.Release(record.Path); err != nil {
+				log.Printf("Could not release %s: %v", record.Path, err)
+			}
+		}
+	}
+
+	if path != "" && retried == 0 {
+		return 0, fmt.Errorf("%s is not in quarantine", path)
+	}
+	return retried, nil
+}
@@ -0,0 +1,230 @@
+package companion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// File is a companion of a video and where it goes once the video is organized.
+type File struct {
+	Source      string
+	Destination string
+}
+
+var (
+	subtitleExts = []string{".srt", ".ass", ".ssa", ".sub", ".idx", ".vtt", ".sup", ".smi"}
+	artworkExts  = []string{".jpg", ".jpeg", ".png", ".tbn"}
+	infoExts     = []string{".nfo"}
+
+	// Folders that usually hold the subtitles of a release
+	subsDirs = []string{"subs", "subtitles", "sub"}
+
+	// Artwork and info that describe the whole folder rather than one file.
+	// They are only taken when the video is alone in its folder.
+	folderFiles = []string{"poster", "folder", "cover", "fanart", "banner", "logo", "clearart", "movie"}
+
+	// Subtitle flags kept in the new name, in this order after the language
+	knownFlags = []string{"forced", "sdh", "cc", "hi", "default"}
+)
+
+// Plan finds the companions of video and names them after destination, the
+// path the video is being organized to. Files matched by base name keep their
+// language and flags, e.g. "movie.en.forced.srt" -> "Title (Year).en.forced.srt".
+// videoExts tells which other files in the folder are videos.
+func Plan(video, destination string, videoExts []string) []File {
+	dir := filepath.Dir(video)
+	base := strings.TrimSuffix(filepath.Base(video), filepath.Ext(video))
+	destDir := filepath.Dir(destination)
+	destBase := strings.TrimSuffix(filepath.Base(destination), filepath.Ext(destination))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	others := videoStems(entries, video, videoExts)
+	alone := len(others) == 0
+
+	var files []File
+	var subs []string
+	taken := make(map[string]bool)
+	add := func(source, name string) {
+		dest := filepath.Join(destDir, uniqueName(name, taken))
+		files = append(files, File{Source: source, Destination: dest})
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		if entry.IsDir() {
+			if slices.Contains(subsDirs, strings.ToLower(name)) {
+				subs = append(subs, subsFolder(path, base, alone)...)
+			}
+			continue
+		}
+		if path == video {
+			continue
+		}
+
+		ext := strings.ToLower(filepath.Ext(name))
+		stem := strings.TrimSuffix(name, filepath.Ext(name))
+		switch {
+		case strings.HasPrefix(stem, base) && isCompanionExt(ext):
+			if !belongs(stem, base, others) {
+				continue // Another file that just starts the same, e.g. "Movie 2.srt"
+			}
+			rest := stem[len(base):]
+			if slices.Contains(subtitleExts, ext) {
+				add(path, destBase+tagsFrom(rest)+ext)
+			} else {
+				// Artwork suffixes like "-poster" are kept as they are
+				add(path, destBase+rest+ext)
+			}
+
+		case alone && isFolderFile(stem, ext):
+			add(path, name)
+		}
+	}
+
+	// After the files next to the video, so those keep the plain names
+	for _, sub := range subs {
+		stem := strings.TrimSuffix(filepath.Base(sub), filepath.Ext(sub))
+		// Tags come after the video name if it is there, e.g. "Movie.en.srt" or "2_English.srt"
+		stem = strings.TrimPrefix(stem, base)
+		add(sub, destBase+tagsFrom(stem)+strings.ToLower(filepath.Ext(sub)))
+	}
+	return files
+}
+
+// FolderFiles finds the artwork and info that describe a whole download folder,
+// e.g. "poster.jpg" or "movie.nfo", and places them in destDir under the same
+// name. Plan only takes them for a video alone in its folder; for a folder
+// handed over as a whole they go with whichever of its videos is placed first.
+func FolderFiles(dir, destDir string) []File {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []File
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if entry.IsDir() || !isFolderFile(strings.TrimSuffix(name, filepath.Ext(name)), ext) {
+			continue
+		}
+		files = append(files, File{Source: filepath.Join(dir, name), Destination: filepath.Join(destDir, name)})
+	}
+	return files
+}
+
+func isFolderFile(stem, ext string) bool {
+	return slices.Contains(folderFiles, strings.ToLower(stem)) && (slices.Contains(artworkExts, ext) || slices.Contains(infoExts, ext))
+}
+
+// subsFolder returns the subtitles of a Subs/ folder that belong to the video:
+// all of them if the video is alone, otherwise those named after it or inside
+// a subfolder named after it (the usual layout of season packs).
+func subsFolder(dir, base string, alone bool) []string {
+	var subs []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if !slices.Contains(subtitleExts, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if alone || strings.HasPrefix(rel, base) {
+			subs = append(subs, path)
+		}
+		return nil
+	})
+	return subs
+}
+
+// belongs tells whether a file whose stem starts with base, the video's, is the
+// video's own and not another's: what follows base must start with a separator
+// and be neither a bare number ("Movie 2.srt", a sequel) nor lead to the name of
+// another video in the folder ("Movie Extended.srt" next to "Movie Extended.mkv").
+func belongs(stem, base string, others []string) bool {
+	rest := stem[len(base):]
+	if rest == "" {
+		return true
+	}
+	if !strings.ContainsAny(rest[:1], ".-_ ") {
+		return false
+	}
+	if number := strings.TrimLeft(rest, ".-_ "); number != "" && strings.IndexFunc(number, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		return false
+	}
+	for _, other := range others {
+		if len(other) > len(base) && strings.HasPrefix(stem, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// videoStems lists the names, without extension, of the videos in the folder
+// other than except
+func videoStems(entries []os.DirEntry, except string, videoExts []string) []string {
+	var stems []string
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == filepath.Base(except) {
+			continue
+		}
+		if ext := filepath.Ext(entry.Name()); slices.Contains(videoExts, strings.ToLower(ext)) {
+			stems = append(stems, strings.TrimSuffix(entry.Name(), ext))
+		}
+	}
+	return stems
+}
+
+func isCompanionExt(ext string) bool {
+	return slices.Contains(subtitleExts, ext) || slices.Contains(artworkExts, ext) || slices.Contains(infoExts, ext)
+}
+
+// tagsFrom builds the ".lang.flags" part of a subtitle name from the words
+// that follow the video name, e.g. ".ENG.Forced" -> ".en.forced" or "2_English" -> ".en".
+func tagsFrom(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var lang string
+	flags := make(map[string]bool)
+	for _, word := range words {
+		if slices.Contains(knownFlags, word) {
+			flags[word] = true
+		} else if code, ok := language(word); ok && lang == "" {
+			lang = code
+		}
+	}
+
+	var tags strings.Builder
+	if lang != "" {
+		tags.WriteString("." + lang)
+	}
+	for _, flag := range knownFlags {
+		if flags[flag] {
+			tags.WriteString("." + flag)
+		}
+	}
+	return tags.String()
+}
+
+// uniqueName numbers a name already used by another companion, keeping the
+// tags right after the video name: "Title.en.srt", "Title.en.2.srt"
+func uniqueName(name string, taken map[string]bool) string {
+	candidate := name
+	ext := filepath.Ext(name)
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	taken[candidate] = true
+	return candidate
+}
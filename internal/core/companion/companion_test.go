@@ -0,0 +1,98 @@
+package companion
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var videoExts = []string{".mkv", ".mp4", ".avi"}
+
+// folder creates the named empty files in a temporary folder
+func folder(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// planned returns the source and destination names of the files Plan finds
+func planned(files []File) map[string]string {
+	names := make(map[string]string, len(files))
+	for _, f := range files {
+		names[filepath.Base(f.Source)] = filepath.Base(f.Destination)
+	}
+	return names
+}
+
+func TestPlanSiblingMovies(t *testing.T) {
+	dir := folder(t,
+		"Movie.mkv", "Movie.en.srt", "Movie-poster.jpg",
+		"Movie 2.mkv", "Movie 2.srt", "Movie 2.nfo",
+	)
+
+	got := planned(Plan(filepath.Join(dir, "Movie.mkv"), "/library/Movie (1999)/Movie (1999).mkv", videoExts))
+	want := map[string]string{
+		"Movie.en.srt":     "Movie (1999).en.srt",
+		"Movie-poster.jpg": "Movie (1999)-poster.jpg",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("first movie got %v, want %v", got, want)
+	}
+
+	got = planned(Plan(filepath.Join(dir, "Movie 2.mkv"), "/library/Movie 2 (2003)/Movie 2 (2003).mkv", videoExts))
+	want = map[string]string{
+		"Movie 2.srt": "Movie 2 (2003).srt",
+		"Movie 2.nfo": "Movie 2 (2003).nfo",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("second movie got %v, want %v", got, want)
+	}
+}
+
+func TestPlanSequelWithoutVideo(t *testing.T) {
+	// The sequel's video is elsewhere, its files still aren't the first movie's
+	dir := folder(t, "Movie.mkv", "Movie.srt", "Movie 2.srt", "Movie.2.nfo")
+
+	got := planned(Plan(filepath.Join(dir, "Movie.mkv"), "/library/Movie (1999)/Movie (1999).mkv", videoExts))
+	want := map[string]string{"Movie.srt": "Movie (1999).srt"}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPlanFolderFilesOnlyWhenAlone(t *testing.T) {
+	dir := folder(t, "Movie.mkv", "poster.jpg", "movie.nfo")
+	got := planned(Plan(filepath.Join(dir, "Movie.mkv"), "/library/Movie (1999)/Movie (1999).mkv", videoExts))
+	if _, ok := got["poster.jpg"]; !ok {
+		t.Errorf("poster of a lone video not taken: %v", got)
+	}
+
+	dir = folder(t, "Movie.mkv", "Other.mkv", "poster.jpg")
+	got = planned(Plan(filepath.Join(dir, "Movie.mkv"), "/library/Movie (1999)/Movie (1999).mkv", videoExts))
+	if _, ok := got["poster.jpg"]; ok {
+		t.Errorf("poster of a shared folder taken: %v", got)
+	}
+}
+
+func TestPlanConfiguredVideoExtensions(t *testing.T) {
+	dir := folder(t, "Movie.mkv", "Other.rmvb", "poster.jpg")
+	video := filepath.Join(dir, "Movie.mkv")
+
+	if got := planned(Plan(video, "/library/Movie (1999)/Movie (1999).mkv", videoExts)); len(got) != 1 {
+		t.Errorf("without .rmvb configured got %v, want the poster", got)
+	}
+	exts := append(videoExts[:len(videoExts):len(videoExts)], ".rmvb")
+	if got := planned(Plan(video, "/library/Movie (1999)/Movie (1999).mkv", exts)); len(got) != 0 {
+		t.Errorf("with .rmvb configured got %v, want nothing", got)
+	}
+}
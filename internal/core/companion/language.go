@@ -0,0 +1,39 @@
+package companion
+
+// languages maps the names and codes found in subtitle file names to ISO 639-1.
+var languages = map[string]string{
+	"en": "en", "eng": "en", "english": "en",
+	"es": "es", "spa": "es", "spanish": "es", "espanol": "es", "español": "es", "latino": "es", "castellano": "es",
+	"fr": "fr", "fre": "fr", "fra": "fr", "french": "fr", "francais": "fr", "français": "fr",
+	"de": "de", "ger": "de", "deu": "de", "german": "de", "deutsch": "de",
+	"it": "it", "ita": "it", "italian": "it", "italiano": "it",
+	"pt": "pt", "por": "pt", "portuguese": "pt", "portugues": "pt", "português": "pt", "brazilian": "pt",
+	"nl": "nl", "dut": "nl", "nld": "nl", "dutch": "nl",
+	"ru": "ru", "rus": "ru", "russian": "ru",
+	"ja": "ja", "jpn": "ja", "japanese": "ja",
+	"zh": "zh", "chi": "zh", "zho": "zh", "chinese": "zh",
+	"ko": "ko", "kor": "ko", "korean": "ko",
+	"ar": "ar", "ara": "ar", "arabic": "ar",
+	"pl": "pl", "pol": "pl", "polish": "pl",
+	"sv": "sv", "swe": "sv", "swedish": "sv",
+	"da": "da", "dan": "da", "danish": "da",
+	"no": "no", "nor": "no", "norwegian": "no",
+	"fi": "fi", "fin": "fi", "finnish": "fi",
+	"tr": "tr", "tur": "tr", "turkish": "tr",
+	"el": "el", "gre": "el", "ell": "el", "greek": "el",
+	"he": "he", "heb": "he", "hebrew": "he",
+	"hu": "hu", "hun": "hu", "hungarian": "hu",
+	"cs": "cs", "cze": "cs", "ces": "cs", "czech": "cs",
+	"ro": "ro", "rum": "ro", "ron": "ro", "romanian": "ro",
+	"uk": "uk", "ukr": "uk", "ukrainian": "uk",
+	"hin": "hi", "hindi": "hi", // "hi" alone means hearing impaired
+
+	"th": "th", "tha": "th", "thai": "th",
+	"vi": "vi", "vie": "vi", "vietnamese": "vi",
+	"id": "id", "ind": "id", "indonesian": "id",
+}
+
+func language(word string) (string, bool) {
+	code, ok := languages[word]
+	return code, ok
+}
@@ -0,0 +1,194 @@
+package conflict
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Policy decides what happens when the destination path is already taken.
+type Policy string
+
+const (
+	Skip      Policy = "skip"
+	Overwrite Policy = "overwrite"
+	KeepBoth  Policy = "keep_both"
+	Upgrade   Policy = "upgrade" // Replace only if the new file is better
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch policy := Policy(s); policy {
+	case Skip, Overwrite, KeepBoth, Upgrade:
+		return policy, nil
+	case "":
+		return Skip, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q (expected skip, overwrite, keep_both or upgrade)", s)
+	}
+}
+
+type Action string
+
+const (
+	ActionSkip    Action = "skip"
+	ActionReplace Action = "replace"
+	ActionRename  Action = "rename"
+)
+
+type Decision struct {
+	Action Action
+	Reason string
+}
+
+// Quality holds what is known about a file for upgrade decisions.
+// Zero values mean unknown.
+type Quality struct {
+	Height   int   // Vertical resolution, e.g. 1080
+	Source   int   // Rank of the release source, see SourceRank
+	Revision int   // 1 for an original release, 2 for a PROPER or REPACK of it
+	Bitrate  int64 // Bits per second
+	Size     int64
+}
+
+// sourceRanks orders release sources from worst to best
+var sourceRanks = []string{"CAM", "HDRip", "DVDRip", "HDTV", "WEBRip", "WEB-DL", "BluRay", "Remux"}
+
+// SourceRank turns a release source as parsed into MediaInfo (e.g. "WEB-DL")
+// into a comparable number, 0 when unknown.
+func SourceRank(source string) int {
+	for i, s := range sourceRanks {
+		if strings.EqualFold(s, source) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Decide applies the policy to a conflict between the file already at the
+// destination and the incoming one. It does not touch the filesystem.
+func Decide(policy Policy, existing, incoming Quality) Decision {
+	switch policy {
+	case Overwrite:
+		return Decision{ActionReplace, "policy is overwrite"}
+	case KeepBoth:
+		return Decision{ActionRename, "policy is keep_both"}
+	case Upgrade:
+		better, reason := isBetter(incoming, existing)
+		if better {
+			return Decision{ActionReplace, reason}
+		}
+		return Decision{ActionSkip, reason}
+	default:
+		return Decision{ActionSkip, "policy is skip"}
+	}
+}
+
+// isBetter compares resolution first, then source, then whether it is a fixed
+// release (PROPER/REPACK), then bitrate, then size. A criterion is only used
+// when it is known for both files and they differ.
+func isBetter(incoming, existing Quality) (bool, string) {
+	if incoming.Height > 0 && existing.Height > 0 && incoming.Height != existing.Height {
+		return incoming.Height > existing.Height,
+			fmt.Sprintf("resolution %dp vs existing %dp", incoming.Height, existing.Height)
+	}
+	if incoming.Source > 0 && existing.Source > 0 && incoming.Source != existing.Source {
+		return incoming.Source > existing.Source,
+			fmt.Sprintf("source %s vs existing %s", sourceRanks[incoming.Source-1], sourceRanks[existing.Source-1])
+	}
+	if incoming.Revision > 0 && existing.Revision > 0 && incoming.Revision != existing.Revision {
+		return incoming.Revision > existing.Revision, "proper/repack of the existing release"
+	}
+	if incoming.Bitrate > 0 && existing.Bitrate > 0 && incoming.Bitrate != existing.Bitrate {
+		return incoming.Bitrate > existing.Bitrate,
+			fmt.Sprintf("bitrate %d vs existing %d", incoming.Bitrate, existing.Bitrate)
+	}
+	if incoming.Size > 0 && existing.Size > 0 && incoming.Size != existing.Size {
+		return incoming.Size > existing.Size,
+			fmt.Sprintf("size %d vs existing %d", incoming.Size, existing.Size)
+	}
+	return false, "not better than the existing file"
+}
+
+// SuffixedPath returns the first free variation of path, e.g. "Titanic (1997) (2).mkv".
+func SuffixedPath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+var resolutionPattern = regexp.MustCompile(`(?i)\b(\d{3,4})[pi]\b|\b(4k|uhd)\b`)
+
+// Probe gathers the quality of a file. Resolution and bitrate come from
+// ffprobe when it is installed, otherwise the resolution is guessed from the name.
+func Probe(path string) (Quality, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Quality{}, err
+	}
+	q := Quality{Size: info.Size()}
+
+	if height, bitrate, err := ffprobe(path); err == nil {
+		q.Height = height
+		q.Bitrate = bitrate
+	}
+	if q.Height == 0 {
+		q.Height = heightFromName(filepath.Base(path))
+	}
+	return q, nil
+}
+
+func heightFromName(name string) int {
+	matches := resolutionPattern.FindStringSubmatch(strings.ReplaceAll(name, ".", " "))
+	if matches == nil {
+		return 0
+	}
+	if matches[2] != "" {
+		return 2160
+	}
+	height, _ := strconv.Atoi(matches[1])
+	return height
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		Height int `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		BitRate string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+func ffprobe(path string) (int, int64, error) {
+	out, err := exec.Command("ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=height:format=bit_rate",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return 0, 0, err
+	}
+
+	var height int
+	if len(probe.Streams) > 0 {
+		height = probe.Streams[0].Height
+	}
+	bitrate, _ := strconv.ParseInt(probe.Format.BitRate, 10, 64)
+	return height, bitrate, nil
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/alejandro-bustamante/flick/internal/quarantine"
+)
+
+// ErrNoResults is returned by finders when nothing matches the parsed media info.
+var ErrNoResults = errors.New("no results")
+
+// ErrParse is returned when the file name does not contain enough to search for.
+var ErrParse = errors.New("could not parse file name")
+
+// ErrChecksum is returned when a file does not match the CRC32 in its name.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// ErrIO is returned when the file itself could not be read, e.g. it is gone or
+// unreadable. Nothing about its name or TMDb is wrong.
+var ErrIO = errors.New("could not read file")
+
+// ErrConflictSkipped is returned when the library already has a file at the
+// destination that the conflict policy keeps, so the new one was left in place.
+var ErrConflictSkipped = errors.New("skipped, the library already has this file")
+
+// reasonFor classifies why a file could not be identified.
+func reasonFor(err error) string {
+	switch {
+	case errors.Is(err, ErrParse):
+		return quarantine.ReasonParseError
+	case errors.Is(err, ErrNoResults):
+		return quarantine.ReasonNoResults
+	case errors.Is(err, ErrChecksum):
+		return quarantine.ReasonBadChecksum
+	case errors.Is(err, ErrIO):
+		return quarantine.ReasonIOError
+	default:
+		// Anything else happened while talking to TMDb, local errors are ErrIO
+		return quarantine.ReasonNetworkError
+	}
+}
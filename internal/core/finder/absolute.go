@@ -0,0 +1,95 @@
+package finders
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/alejandro-bustamante/flick/internal/core"
+)
+
+// absoluteGroupType is the type TMDb gives to "Absolute" episode groups
+const absoluteGroupType = 2
+
+type EpisodeGroupsResponse struct {
+	Results []struct {
+		ID           string `json:"id"`
+		Type         int    `json:"type"`
+		EpisodeCount int    `json:"episode_count"`
+	} `json:"results"`
+}
+
+type EpisodeGroupDetailsResponse struct {
+	Groups []EpisodeGroup `json:"groups"`
+}
+
+type EpisodeGroup struct {
+	Order    int                 `json:"order"`
+	Episodes []EpisodeGroupEntry `json:"episodes"`
+}
+
+type EpisodeGroupEntry struct {
+	Order         int `json:"order"`
+	SeasonNumber  int `json:"season_number"`
+	EpisodeNumber int `json:"episode_number"`
+}
+
+// resolveAbsolute maps an episode counted from the start of the show, as anime
+// fansubs number them, to a season and episode. An absolute episode group of the
+// show is used when it has one, otherwise the regular seasons are counted in order.
+func (f *TMDBFinder) resolveAbsolute(ctx context.Context, seriesID, absolute int) (int, int, error) {
+	season, episode, err := f.absoluteFromGroup(ctx, seriesID, absolute)
+	if err != nil || season > 0 || episode > 0 {
+		return season, episode, err
+	}
+
+	var show TVSeasonsResponse
+	if err := f.getJSON(ctx, fmt.Sprintf("https://api.themoviedb.org/3/tv/%d?language=en-US", seriesID), &show); err != nil {
+		return 0, 0, err
+	}
+	remaining := absolute
+	for _, s := range show.Seasons {
+		if s.SeasonNumber == 0 {
+			continue // Specials are not part of the count
+		}
+		if remaining <= s.EpisodeCount {
+			return s.SeasonNumber, remaining, nil
+		}
+		remaining -= s.EpisodeCount
+	}
+	return 0, 0, fmt.Errorf("%w: series %d has fewer than %d episodes", core.ErrNoResults, seriesID, absolute)
+}
+
+// absoluteFromGroup looks the episode up in the largest absolute episode group
+// of the show. It returns 0, 0 if there is no such group or it is too short.
+func (f *TMDBFinder) absoluteFromGroup(ctx context.Context, seriesID, absolute int) (int, int, error) {
+	var groups EpisodeGroupsResponse
+	if err := f.getJSON(ctx, fmt.Sprintf("https://api.themoviedb.org/3/tv/%d/episode_groups", seriesID), &groups); err != nil {
+		return 0, 0, err
+	}
+	groupID, count := "", 0
+	for _, g := range groups.Results {
+		if g.Type == absoluteGroupType && g.EpisodeCount > count {
+			groupID, count = g.ID, g.EpisodeCount
+		}
+	}
+	if groupID == "" || count < absolute {
+		return 0, 0, nil
+	}
+
+	var details EpisodeGroupDetailsResponse
+	if err := f.getJSON(ctx, "https://api.themoviedb.org/3/tv/episode_group/"+groupID, &details); err != nil {
+		return 0, 0, err
+	}
+	slices.SortFunc(details.Groups, func(a, b EpisodeGroup) int { return a.Order - b.Order })
+	remaining := absolute
+	for _, g := range details.Groups {
+		if remaining <= len(g.Episodes) {
+			slices.SortFunc(g.Episodes, func(a, b EpisodeGroupEntry) int { return a.Order - b.Order })
+			e := g.Episodes[remaining-1]
+			return e.SeasonNumber, e.EpisodeNumber, nil
+		}
+		remaining -= len(g.Episodes)
+	}
+	return 0, 0, nil
+}
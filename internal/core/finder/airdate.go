@@ -0,0 +1,85 @@
+package finders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alejandro-bustamante/flick/internal/core"
+)
+
+type TVSeasonsResponse struct {
+	ID      int `json:"id"`
+	Seasons []struct {
+		SeasonNumber int    `json:"season_number"`
+		AirDate      string `json:"air_date"`
+		EpisodeCount int    `json:"episode_count"`
+	} `json:"seasons"`
+}
+
+type SeasonDetailsResponse struct {
+	SeasonNumber int                      `json:"season_number"`
+	Episodes     []EpisodeDetailsResponse `json:"episodes"`
+}
+
+// resolveAirDate finds the episode of a series that aired on airDate (YYYY-MM-DD).
+// Daily shows are numbered by TMDb like any other, so the date is looked up in
+// the season that was running at the time, or the one before if it isn't there.
+func (f *TMDBFinder) resolveAirDate(ctx context.Context, seriesID int, airDate string) (*EpisodeDetailsResponse, error) {
+	var show TVSeasonsResponse
+	if err := f.getJSON(ctx, fmt.Sprintf("https://api.themoviedb.org/3/tv/%d?language=en-US", seriesID), &show); err != nil {
+		return nil, err
+	}
+
+	// Candidates are the regular seasons that started on or before the date, latest first.
+	// Dates in YYYY-MM-DD compare correctly as strings.
+	var candidates []int
+	for i := len(show.Seasons) - 1; i >= 0; i-- {
+		season := show.Seasons[i]
+		if season.SeasonNumber > 0 && season.AirDate != "" && season.AirDate <= airDate {
+			candidates = append(candidates, season.SeasonNumber)
+		}
+		if len(candidates) == 2 {
+			break
+		}
+	}
+
+	for _, number := range candidates {
+		var season SeasonDetailsResponse
+		if err := f.getJSON(ctx, fmt.Sprintf("https://api.themoviedb.org/3/tv/%d/season/%d?language=en-US", seriesID, number), &season); err != nil {
+			return nil, err
+		}
+		for _, episode := range season.Episodes {
+			if episode.AirDate == airDate {
+				return &episode, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: no episode of series %d aired on %s", core.ErrNoResults, seriesID, airDate)
+}
+
+// getJSON fetches a TMDb endpoint and decodes the response into out.
+func (f *TMDBFinder) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+f.APIKey)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s not found", core.ErrNoResults, url)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from %s: %s", url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -1,6 +1,7 @@
 package finders
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -44,6 +45,14 @@ type TVDetailsResponse struct {
 	FirstAirDate string `json:"first_air_date"`
 }
 
+type EpisodeDetailsResponse struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	AirDate       string `json:"air_date"`
+}
+
 type TMDBFinder struct {
 	APIKey string
 	parser core.Parser
@@ -81,35 +90,81 @@ func getYearDigitDiff(year1 int, year2Str string) int {
 	return diffCount
 }
 
-func (f *TMDBFinder) GetMediaInfo(mediaInfo models.MediaInfo) (*models.MediaInfo, error) {
-	bestID, certainty, err := f.searchForID(mediaInfo)
+func (f *TMDBFinder) GetMediaInfo(ctx context.Context, mediaInfo models.MediaInfo) (*models.MediaInfo, error) {
+	bestID, certainty, err := f.searchForID(ctx, mediaInfo)
 	mediaInfo.Accuracy = certainty
 	if err != nil {
 		return nil, err
 	}
 
 	if bestID == 0 {
-		return nil, fmt.Errorf("could not find results for: %s", mediaInfo.Title)
+		return nil, fmt.Errorf("%w: could not find results for: %s", core.ErrNoResults, mediaInfo.Title)
 	}
 
-	title, year, err := f.getDetailsByID(bestID, mediaInfo.IsSeries)
+	return f.GetMediaInfoByID(ctx, mediaInfo, bestID)
+}
+
+// GetMediaInfoByID skips the search and builds the media info from a known TMDb ID,
+// e.g. when a user re-matches a file by hand.
+func (f *TMDBFinder) GetMediaInfoByID(ctx context.Context, mediaInfo models.MediaInfo, id int) (*models.MediaInfo, error) {
+	title, year, err := f.getDetailsByID(ctx, id, mediaInfo.IsSeries)
 	if err != nil {
 		return nil, err
 	}
 
 	year_int, _ := strconv.Atoi(year)
 
+	if mediaInfo.IsSeries && mediaInfo.Episode == 0 && mediaInfo.AirDate != "" {
+		// A daily show, only the date is known
+		episode, err := f.resolveAirDate(ctx, id, mediaInfo.AirDate)
+		if err != nil {
+			return nil, err
+		}
+		mediaInfo.Season = episode.SeasonNumber
+		mediaInfo.Episode = episode.EpisodeNumber
+		mediaInfo.Episodes = []int{episode.EpisodeNumber}
+	} else if mediaInfo.IsSeries && mediaInfo.Episode == 0 && mediaInfo.AbsoluteEpisode > 0 {
+		// Anime, only the episode counted from the start of the show is known
+		season, episode, err := f.resolveAbsolute(ctx, id, mediaInfo.AbsoluteEpisode)
+		if err != nil {
+			return nil, err
+		}
+		mediaInfo.Season = season
+		mediaInfo.Episode = episode
+		mediaInfo.Episodes = []int{episode}
+	}
+
+	var episodeTitle string
+	if mediaInfo.IsSeries {
+		// Also confirms the parsed episode actually exists in that season
+		episode, err := f.getEpisodeDetails(ctx, id, mediaInfo.Season, mediaInfo.Episode)
+		if err != nil {
+			return nil, err
+		}
+		episodeTitle = episode.Name
+	}
+
 	return &models.MediaInfo{
-		Title:    title,
-		Year:     year_int,
-		IsSeries: mediaInfo.IsSeries,
-		Season:   mediaInfo.Season,
-		Episode:  mediaInfo.Episode,
-		Accuracy: mediaInfo.Accuracy,
+		Title:        title,
+		Year:         year_int,
+		IsSeries:     mediaInfo.IsSeries,
+		Season:       mediaInfo.Season,
+		Episode:      mediaInfo.Episode,
+		Episodes:     mediaInfo.Episodes,
+		EpisodeTitle: episodeTitle,
+		AirDate:      mediaInfo.AirDate,
+		Accuracy:     mediaInfo.Accuracy,
+		TMDBID:       id,
+		// Kept so the name can still be checked against the file
+		AbsoluteEpisode: mediaInfo.AbsoluteEpisode,
+		CRC32:           mediaInfo.CRC32,
+		Part:            mediaInfo.Part,
+		// TMDb knows nothing about the file itself, keep what the name told us
+		Release: mediaInfo.Release,
 	}, nil
 }
 
-func (f *TMDBFinder) searchForID(mediaInfo models.MediaInfo) (bestID int, accuracy int, err error) {
+func (f *TMDBFinder) searchForID(ctx context.Context, mediaInfo models.MediaInfo) (bestID int, accuracy int, err error) {
 	var baseURL string
 	if mediaInfo.IsSeries {
 		baseURL = "https://api.themoviedb.org/3/search/tv"
@@ -139,7 +194,7 @@ func (f *TMDBFinder) searchForID(mediaInfo models.MediaInfo) (bestID int, accura
 
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -168,10 +223,6 @@ func (f *TMDBFinder) searchForID(mediaInfo models.MediaInfo) (bestID int, accura
 	}
 
 	// --- Match accuracy logic ---
-	if mediaInfo.IsSeries {
-		return searchResponse.Results[0].ID, 0, nil
-	}
-
 	bestID = searchResponse.Results[0].ID
 	bestCertainty := 1
 
@@ -179,11 +230,16 @@ func (f *TMDBFinder) searchForID(mediaInfo models.MediaInfo) (bestID int, accura
 
 	for _, result := range searchResponse.Results {
 		currentCertainty := 0
-		normalizedApiTitle := f.parser.NormalizeForComparison(result.Title)
+		// Series use name and first air date instead of title and release date
+		apiTitle, apiDate := result.Title, result.ReleaseDate
+		if mediaInfo.IsSeries {
+			apiTitle, apiDate = result.Name, result.FirstAirDate
+		}
+		normalizedApiTitle := f.parser.NormalizeForComparison(apiTitle)
 
 		apiYearStr := ""
-		if result.ReleaseDate != "" {
-			parts := strings.Split(result.ReleaseDate, "-")
+		if apiDate != "" {
+			parts := strings.Split(apiDate, "-")
 			if len(parts) > 0 {
 				apiYearStr = parts[0]
 			}
@@ -231,7 +287,7 @@ func (f *TMDBFinder) searchForID(mediaInfo models.MediaInfo) (bestID int, accura
 	return bestID, bestCertainty, nil
 }
 
-func (f *TMDBFinder) getDetailsByID(id int, isSeries bool) (string, string, error) {
+func (f *TMDBFinder) getDetailsByID(ctx context.Context, id int, isSeries bool) (string, string, error) {
 	var baseURL string
 	if isSeries {
 		baseURL = "https://api.themoviedb.org/3/tv/"
@@ -241,7 +297,7 @@ func (f *TMDBFinder) getDetailsByID(id int, isSeries bool) (string, string, erro
 
 	url := baseURL + strconv.Itoa(id) + "?language=en-US"
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", "", err
 	}
@@ -294,3 +350,40 @@ func (f *TMDBFinder) getDetailsByID(id int, isSeries bool) (string, string, erro
 		return movieDetails.Title, year, nil
 	}
 }
+
+func (f *TMDBFinder) getEpisodeDetails(ctx context.Context, seriesID, season, episode int) (*EpisodeDetailsResponse, error) {
+	url := fmt.Sprintf("https://api.themoviedb.org/3/tv/%d/season/%d/episode/%d?language=en-US", seriesID, season, episode)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+f.APIKey)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: episode S%02dE%02d does not exist for series %d", core.ErrNoResults, season, episode, seriesID)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching episode S%02dE%02d: %s", season, episode, res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var details EpisodeDetailsResponse
+	if err := json.Unmarshal(body, &details); err != nil {
+		return nil, err
+	}
+
+	return &details, nil
+}
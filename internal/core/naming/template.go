@@ -0,0 +1,303 @@
+package naming
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	models "github.com/alejandro-bustamante/flick/internal/models"
+)
+
+const (
+	DefaultMovieTemplate  = "{title} ({year})/{title} ({year}){ext}"
+	DefaultSeriesTemplate = "{title}/{season_folder}/{title} - S{season:02}E{episode:02}{ext}"
+)
+
+// Template renders a destination path, relative to the library root, from a MediaInfo.
+// Placeholders are written as {field} or {field:02}, where field is the snake_case
+// name of any MediaInfo field (plus {ext}) and the optional spec is a minimum width,
+// zero padded when it starts with 0. For a multi-episode file {episode} renders
+// the whole range, e.g. S{season:02}E{episode:02} -> S01E01-E03. {season_folder}
+// renders "Season 1", or "Specials" for season 0. The part of a multi-part movie
+// is added as "Movie (1999) - cd1.avi" when the template has no {part}.
+type Template struct {
+	raw   string
+	parts []part
+}
+
+type part struct {
+	literal string
+	field   string
+	width   int
+	zeroPad bool
+}
+
+var specPattern = regexp.MustCompile(`^(0?)([1-9]\d*)$`)
+
+func Parse(raw string) (*Template, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("template is empty")
+	}
+	if filepath.IsAbs(raw) {
+		return nil, fmt.Errorf("template %q must be relative to the library directory", raw)
+	}
+	for _, segment := range strings.FieldsFunc(raw, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if strings.TrimSpace(segment) == ".." {
+			return nil, fmt.Errorf("template %q must stay inside the library directory", raw)
+		}
+	}
+
+	known := Fields()
+	t := &Template{raw: raw}
+	rest := raw
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			t.parts = append(t.parts, part{literal: rest})
+			break
+		}
+		if open > 0 {
+			t.parts = append(t.parts, part{literal: rest[:open]})
+		}
+
+		closing := strings.IndexByte(rest[open:], '}')
+		if closing < 0 {
+			return nil, fmt.Errorf("template %q has an unclosed placeholder", raw)
+		}
+		placeholder := rest[open+1 : open+closing]
+		rest = rest[open+closing+1:]
+
+		name, spec, _ := strings.Cut(placeholder, ":")
+		p := part{field: strings.TrimSpace(name)}
+		if _, ok := known[p.field]; !ok {
+			return nil, fmt.Errorf("template %q uses unknown field {%s}", raw, p.field)
+		}
+		if spec != "" {
+			matches := specPattern.FindStringSubmatch(spec)
+			if matches == nil {
+				return nil, fmt.Errorf("template %q has an invalid format %q for {%s}", raw, spec, p.field)
+			}
+			p.zeroPad = matches[1] == "0"
+			p.width, _ = strconv.Atoi(matches[2])
+		}
+		t.parts = append(t.parts, p)
+	}
+
+	return t, nil
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Render builds the relative destination path. Values are made safe to use as a path
+// component, and separators left dangling by empty values are cleaned up.
+func (t *Template) Render(info *models.MediaInfo, ext string) string {
+	values := values(info)
+	values["ext"] = ext
+
+	var b strings.Builder
+	for i, p := range t.parts {
+		if p.field == "" {
+			b.WriteString(p.literal)
+			continue
+		}
+		value := format(values[p.field], p.width, p.zeroPad)
+		switch {
+		case p.field == "episode" && len(info.Episodes) > 1:
+			value += episodeRangeEnd(info.Episodes, p, t.parts[:i])
+		case p.field == "season" && info.IsSeries && value == "":
+			// Season 0 holds the specials, it isn't missing
+			value = format("0", p.width, p.zeroPad)
+		case p.field == "season_folder":
+			value = seasonFolder(info, p)
+		}
+		if p.field != "ext" {
+			value = sanitize(value)
+		}
+		b.WriteString(value)
+	}
+
+	rendered := b.String()
+	if info.Part != "" && !t.uses("part") && ext != "" && strings.HasSuffix(rendered, ext) {
+		// The parts of a movie would all land on the same path otherwise
+		rendered = strings.TrimSuffix(rendered, ext) + " - " + sanitize(info.Part) + ext
+	}
+
+	segments := strings.Split(filepath.ToSlash(rendered), "/")
+	cleaned := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		if i == len(segments)-1 && ext != "" && strings.HasSuffix(segment, ext) {
+			segment = tidy(strings.TrimSuffix(segment, ext)) + ext
+		} else {
+			segment = tidy(segment)
+		}
+		if segment != "" {
+			cleaned = append(cleaned, segment)
+		}
+	}
+
+	return filepath.Join(cleaned...)
+}
+
+func (t *Template) uses(field string) bool {
+	for _, p := range t.parts {
+		if p.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// episodeRangeEnd completes {episode} for a multi-episode file, repeating the
+// letter written before the placeholder: S{season:02}E{episode:02} gives
+// "S01E01-E02" and {season}x{episode:02} gives "1x01-02".
+func episodeRangeEnd(episodes []int, p part, before []part) string {
+	last := episodes[len(episodes)-1]
+	if last == episodes[0] {
+		return ""
+	}
+	prefix := ""
+	if n := len(before); n > 0 && before[n-1].field == "" {
+		if literal := before[n-1].literal; strings.HasSuffix(literal, "E") || strings.HasSuffix(literal, "e") {
+			prefix = literal[len(literal)-1:]
+		}
+	}
+	return "-" + prefix + format(last, p.width, p.zeroPad)
+}
+
+// seasonFolder renders {season_folder}, with the width applied to the number:
+// {season_folder:02} gives "Season 02".
+func seasonFolder(info *models.MediaInfo, p part) string {
+	if !info.IsSeries {
+		return ""
+	}
+	if info.Season == 0 {
+		return "Specials"
+	}
+	return "Season " + format(info.Season, p.width, p.zeroPad)
+}
+
+// Fields returns the set of placeholder names a template may use.
+func Fields() map[string]struct{} {
+	fields := map[string]struct{}{"ext": {}, "season_folder": {}}
+	for name := range values(&models.MediaInfo{}) {
+		fields[name] = struct{}{}
+	}
+	return fields
+}
+
+// values exposes every MediaInfo field by its snake_case name, or by its naming tag
+// when the derived name would be unreadable (e.g. TMDBID). Fields of embedded
+// structs (Release) are exposed as if they were MediaInfo's own.
+func values(info *models.MediaInfo) map[string]any {
+	values := make(map[string]any)
+	collect(reflect.ValueOf(info).Elem(), values)
+	return values
+}
+
+func collect(v reflect.Value, values map[string]any) {
+	typ := v.Type()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collect(v.Field(i), values)
+			continue
+		}
+		name := field.Tag.Get("naming")
+		if name == "" {
+			name = snakeCase(field.Name)
+		}
+		value := v.Field(i).Interface()
+		if flag, ok := value.(bool); ok {
+			// A set flag renders as its name, e.g. {proper} -> PROPER
+			value = ""
+			if flag {
+				value = strings.ToUpper(name)
+			}
+		}
+		values[name] = value
+	}
+}
+
+func format(value any, width int, zeroPad bool) string {
+	pad := " "
+	if zeroPad {
+		pad = "0"
+	}
+
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	case int:
+		// Zero means unknown (e.g. no year) unless a zero padded width asks for it, as in S{season:02}
+		if v == 0 && !zeroPad {
+			return ""
+		}
+		s = strconv.Itoa(v)
+	case []string:
+		s = strings.Join(v, " ")
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" {
+		return ""
+	}
+	if n := width - len(s); n > 0 {
+		s = strings.Repeat(pad, n) + s
+	}
+	return s
+}
+
+var invalidChars = strings.NewReplacer(
+	"/", "-", "\\", "-",
+	"<", "", ">", "", ":", "", "\"", "", "|", "", "?", "", "*", "",
+)
+
+func sanitize(value string) string {
+	return invalidChars.Replace(value)
+}
+
+var (
+	emptyGroups      = regexp.MustCompile(`\(\s*\)|\[\s*\]|\{\s*\}`)
+	repeatedSpaces   = regexp.MustCompile(`\s{2,}`)
+	danglingDashes   = regexp.MustCompile(`(\s+-)+\s*$|^\s*(-\s+)+`)
+	doubledSeparator = regexp.MustCompile(`\s+-(\s+-)+\s+`)
+)
+
+func tidy(segment string) string {
+	segment = emptyGroups.ReplaceAllString(segment, "")
+	segment = doubledSeparator.ReplaceAllString(segment, " - ")
+	segment = repeatedSpaces.ReplaceAllString(segment, " ")
+	segment = danglingDashes.ReplaceAllString(segment, "")
+	return strings.Trim(segment, " .")
+}
+
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
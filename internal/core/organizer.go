@@ -1,108 +1,539 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/alejandro-bustamante/flick/internal/core/companion"
+	"github.com/alejandro-bustamante/flick/internal/core/conflict"
+	"github.com/alejandro-bustamante/flick/internal/core/naming"
+	"github.com/alejandro-bustamante/flick/internal/core/transfer"
+	"github.com/alejandro-bustamante/flick/internal/journal"
 	"github.com/alejandro-bustamante/flick/internal/models"
+	"github.com/alejandro-bustamante/flick/internal/quarantine"
+	"github.com/alejandro-bustamante/flick/internal/review"
 	"github.com/alejandro-bustamante/flick/internal/watcher"
 )
 
 type Finder interface {
-	GetMediaInfo(mediaInfo models.MediaInfo) (*models.MediaInfo, error)
+	GetMediaInfo(ctx context.Context, mediaInfo models.MediaInfo) (*models.MediaInfo, error)
+	GetMediaInfoByID(ctx context.Context, mediaInfo models.MediaInfo, id int) (*models.MediaInfo, error)
 }
 
 type Parser interface {
 	Parse(filename string) *models.ParseResult
 	ParseNormalized(filename string) *models.ParseResult
 	NormalizeForComparison(input string) string
+	ParsePath(relPath string) *models.ParseResult
+}
+
+// Profile is a watch folder and the library its files are organized into.
+type Profile struct {
+	Name           string
+	Watcher        *watcher.FolderWatcher
+	MoviesDir      string
+	SeriesDir      string
+	MovieTemplate  *naming.Template
+	SeriesTemplate *naming.Template
+	TransferMode   transfer.Mode
+}
+
+type OrganizerConfig struct {
+	ReviewDir      string // Optional holding folder for matches waiting for review
+	ConflictPolicy conflict.Policy
+	MinAccuracy    int // Matches below this accuracy (0-5) go to the review queue
+	Workers        int // Files processed concurrently by each pipeline stage
+	// Check files named with a CRC32, e.g. "[ABCD1234]", against it before organizing them
+	VerifyChecksums bool
+	// The [filter] extensions of patterns.toml, they tell companions apart from
+	// other videos sharing a folder. Defaults to watcher.DefaultVideoExtensions
+	VideoExtensions []string
 }
 
 type Organizer struct {
-	parser    Parser
-	finder    Finder
-	watcher   *watcher.FolderWatcher
-	watchDir  string
-	moviesDir string
-	seriesDir string
+	Config     OrganizerConfig
+	parser     Parser
+	finder     Finder
+	profiles   []*Profile
+	journal    *journal.Journal
+	review     *review.Queue
+	quarantine *quarantine.Quarantine // nil when no unmatched folder is configured
+	batchID    string
+	// Files can be placed both by the pipeline and by review commands
+	destLocks keyedMutex
+	// Jobs already accepted by the pipeline keep running on workCtx after Run's
+	// context is cancelled, until Wait gives up on them
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+	done       chan struct{}
 }
 
-func NewOrganizer(p Parser, f Finder, w *watcher.FolderWatcher, moviesDir, seriesDir string) *Organizer {
+// NewOrganizer needs at least one profile. The first one also takes the files
+// that don't belong to any watch folder, e.g. review items from an older layout.
+func NewOrganizer(p Parser, f Finder, profiles []*Profile, j *journal.Journal, r *review.Queue, q *quarantine.Quarantine, config OrganizerConfig) *Organizer {
+	if len(config.VideoExtensions) == 0 {
+		config.VideoExtensions = watcher.DefaultVideoExtensions
+	}
 	return &Organizer{
-		parser:    p,
-		finder:    f,
-		watcher:   w,
-		watchDir:  w.Config.Path,
-		moviesDir: moviesDir,
-		seriesDir: seriesDir,
+		Config:     config,
+		parser:     p,
+		finder:     f,
+		profiles:   profiles,
+		journal:    j,
+		review:     r,
+		quarantine: q,
+		// Every file moved during this run shares a batch, so the whole run can be undone at once
+		batchID: j.NewBatch(),
 	}
 }
 
-func (o *Organizer) Run() {
-	if err := o.watcher.Start(); err != nil {
-		log.Fatalf("Error starting watcher: %v", err)
+// Run starts the watchers and the pipeline and returns. Cancelling ctx stops the
+// watchers, after which the pipeline drains what it already accepted; use Wait
+// to block until that is done.
+func (o *Organizer) Run(ctx context.Context) error {
+	ctx, stop := context.WithCancel(ctx)
+	for _, profile := range o.profiles {
+		if err := profile.Watcher.Start(ctx); err != nil {
+			stop() // The watchers already started
+			return fmt.Errorf("error starting watcher for profile %s: %v", profile.Name, err)
+		}
 	}
 
-	log.Println("Organizer is running and listening for stable files...")
+	log.Printf("Organizer is running and listening for stable files from %d profile(s)...", len(o.profiles))
+
+	o.workCtx, o.cancelWork = context.WithCancel(context.WithoutCancel(ctx))
+	o.done = make(chan struct{})
+
+	// All watchers feed the same pipeline, files are routed by their path
+	groups := make(chan watcher.Group)
+	var watchers sync.WaitGroup
+	for _, profile := range o.profiles {
+		watchers.Add(1)
+		go func() {
+			defer watchers.Done()
+			for group := range profile.Watcher.StableFiles {
+				groups <- group
+			}
+		}()
+	}
+	go func() {
+		watchers.Wait()
+		stop()
+		close(groups)
+	}()
 
+	p := newPipeline(o, o.Config.Workers)
 	go func() {
-		for filePath := range o.watcher.StableFiles {
-			log.Printf("Organizer received stable file: %s", filePath)
-
-			// Process the file
-			destinationPath := o.GetDestinationPath(filePath)
-			destinationDir := filepath.Dir(destinationPath)
-			if destinationPath == "" {
-				log.Printf("Could not determine final path for: %s", filePath)
-				continue
+		defer close(o.done)
+		p.run(o.workCtx, groups)
+		log.Println("Watcher channels closed. Organizer stopping.")
+	}()
+	return nil
+}
+
+// profileFor returns the profile whose watch folder contains filePath, the
+// innermost one if they are nested. Files outside every watch folder go to
+// the first profile.
+func (o *Organizer) profileFor(filePath string) *Profile {
+	best, bestLen := o.profiles[0], -1
+	for _, profile := range o.profiles {
+		dir := filepath.Clean(profile.Watcher.Config.Path)
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(dir) > bestLen {
+			best, bestLen = profile, len(dir)
+		}
+	}
+	return best
+}
+
+// Wait blocks until the pipeline has drained after Run's context was cancelled.
+// If that takes longer than timeout, lookups still in flight are cancelled and
+// queued files are left where they are for the next run. A transfer that has
+// already started is always allowed to finish.
+func (o *Organizer) Wait(timeout time.Duration) {
+	if o.done == nil {
+		return
+	}
+
+	select {
+	case <-o.done:
+	case <-time.After(timeout):
+		log.Printf("Pipeline did not drain in %s, cancelling pending work", timeout)
+		o.cancelWork()
+		<-o.done
+	}
+	o.cancelWork()
+}
+
+// Handled reports whether flick already dealt with the file: it was organized or
+// skipped, is waiting for review or is in quarantine. Watcher scans use it to skip them.
+func (o *Organizer) Handled(filePath string) bool {
+	return o.journal.Handled(filePath) ||
+		o.review.Contains(filePath) ||
+		(o.quarantine != nil && o.quarantine.Contains(filePath))
+}
+
+// accepts filters out files that are already parked somewhere by flick
+func (o *Organizer) accepts(filePath string) bool {
+	if o.review.Contains(filePath) {
+		log.Printf("File is waiting for review, ignoring: %s", filePath)
+		return false
+	}
+	if o.quarantine != nil && o.quarantine.Contains(filePath) {
+		// Only an explicit retry takes files out of quarantine
+		return false
+	}
+	if o.journal.Handled(filePath) {
+		log.Printf("File was already organized or skipped, ignoring: %s", filePath)
+		return false
+	}
+	return true
+}
+
+// organize identifies and places a file using the given profile. foundAt is where
+// the file was first found, which is what gets parsed, e.g. the download folder
+// of a file retried from quarantine. It returns an error when the file could not
+// be handled and was sent to quarantine (or left in place).
+func (o *Organizer) organize(ctx context.Context, filePath, foundAt string, profile *Profile) error {
+	mediaInfo, err := o.identify(ctx, filePath, foundAt)
+	if err != nil {
+		log.Printf("Could not determine final path for: %s (%v)", filePath, err)
+		o.quarantineFile(filePath, reasonFor(err), err, profile)
+		return err
+	}
+
+	if mediaInfo.Accuracy < o.Config.MinAccuracy {
+		o.holdForReview(filePath, mediaInfo, profile)
+		return nil
+	}
+
+	err = o.place(filePath, mediaInfo, profile)
+	if errors.Is(err, ErrParse) {
+		o.quarantineFile(filePath, quarantine.ReasonParseError, err, profile)
+	}
+	return err
+}
+
+// quarantineFile moves a file that could not be organized to the unmatched folder.
+// Without one configured the file just stays where it is, skipped until it changes,
+// and so does a file whose profile keeps its sources, only its record is written.
+func (o *Organizer) quarantineFile(filePath, reason string, cause error, profile *Profile) {
+	if _, err := os.Lstat(filePath); os.IsNotExist(err) {
+		log.Printf("%s is gone, nothing to quarantine", filePath)
+		return
+	}
+	if o.quarantine == nil {
+		o.skip(filePath, reason)
+		return
+	}
+	record, err := o.quarantine.Add(filePath, reason, cause, profile.TransferMode)
+	if err != nil {
+		log.Printf("Could not quarantine %s: %v", filePath, err)
+		return
+	}
+	if record.InPlace {
+		// Outside the unmatched folder, rescans need the journal to leave it alone
+		if err := o.journal.Skip(filePath, reason); err != nil {
+			log.Printf("Could not record %s as skipped: %v", filePath, err)
+		}
+		log.Printf("Quarantined %s in place (%s, attempt %d)", filePath, reason, record.Attempts)
+		return
+	}
+	log.Printf("Quarantined %s as %s (%s, attempt %d)", filePath, record.Path, reason, record.Attempts)
+}
+
+// skip records a file that is left where it is, so rescans and restarts don't
+// look it up again
+func (o *Organizer) skip(filePath, reason string) {
+	if err := o.journal.Skip(filePath, reason); err != nil {
+		log.Printf("Could not record %s as skipped: %v", filePath, err)
+		return
+	}
+	log.Printf("Skipped %s (%s), it is left in place until it changes", filePath, reason)
+}
+
+// holdForReview queues a low accuracy match and, if configured, moves the file
+// to the holding folder so it is out of the way. A profile that keeps its sources
+// (copy, links) leaves the file where it is, it is only placed once approved.
+func (o *Organizer) holdForReview(filePath string, mediaInfo *models.MediaInfo, profile *Profile) {
+	currentPath := filePath
+	if o.Config.ReviewDir != "" && !profile.TransferMode.KeepsSource() {
+		heldPath := filepath.Join(o.Config.ReviewDir, filepath.Base(filePath))
+		if _, err := os.Lstat(heldPath); err == nil {
+			heldPath = conflict.SuffixedPath(heldPath)
+		}
+		os.MkdirAll(o.Config.ReviewDir, 0777)
+		// Planned before the move, while the companions still sit next to the file
+		companions := companion.Plan(filePath, heldPath, o.Config.VideoExtensions)
+		if _, err := transfer.Transfer(filePath, heldPath, transfer.Move); err != nil {
+			log.Printf("Could not move %s to the review folder, leaving it in place: %v", filePath, err)
+		} else {
+			currentPath = heldPath
+			// Named after the held file so they are found again when it is approved
+			for _, file := range companions {
+				if _, err := os.Lstat(file.Destination); err == nil {
+					continue
+				}
+				if _, err := transfer.Transfer(file.Source, file.Destination, transfer.Move); err != nil {
+					log.Printf("Could not move companion %s to the review folder: %v", file.Source, err)
+				}
 			}
+		}
+	}
+
+	item, err := o.review.Add(review.Item{
+		Path:         currentPath,
+		OriginalPath: filePath,
+		Candidate:    *mediaInfo,
+	})
+	if err != nil {
+		log.Printf("Could not add %s to the review queue: %v", filePath, err)
+		return
+	}
+	log.Printf("Accuracy %d is below %d, %s queued for review as %s (candidate: %s)",
+		mediaInfo.Accuracy, o.Config.MinAccuracy, filePath, item.ID, mediaInfo.Title)
+}
+
+// place moves the file to its final destination in the profile's library and
+// records it in the journal.
+func (o *Organizer) place(filePath string, mediaInfo *models.MediaInfo, profile *Profile) error {
+	destinationPath := o.destinationFor(filePath, mediaInfo, profile)
+	if destinationPath == "" {
+		log.Printf("Could not determine final path for: %s", filePath)
+		return fmt.Errorf("%w: could not determine final path for %s", ErrParse, filePath)
+	}
+	return o.placeAt(filePath, mediaInfo, destinationPath, profile.TransferMode, nil)
+}
+
+// placeAt does the actual transfer to an already planned destination. Only one
+// file at a time can be placed at a given destination. folder is the download
+// folder the file was handed over with, if any.
+func (o *Organizer) placeAt(filePath string, mediaInfo *models.MediaInfo, destinationPath string, transferMode transfer.Mode, folder *folderGroup) error {
+	unlock := o.destLocks.Lock(destinationPath)
+	defer unlock()
+
+	destinationDir := filepath.Dir(destinationPath)
 
-			// Permisions that allows to read and write for any user
-			dirPerm := 0777
-			os.MkdirAll(destinationDir, os.FileMode(dirPerm))
-			err := os.Rename(filePath, destinationPath)
-			if err != nil {
-				log.Printf("Could not move to final path. Error: %s", err)
+	// Permisions that allows to read and write for any user
+	dirPerm := 0777
+	os.MkdirAll(destinationDir, os.FileMode(dirPerm))
+
+	var replaced string
+	var previous journal.Operation // What put the replaced file there, if flick did
+	if _, err := os.Lstat(destinationPath); err == nil {
+		decision := o.decideConflict(filePath, destinationPath, mediaInfo)
+		log.Printf("Conflict at %s: %s (%s)", destinationPath, decision.Action, decision.Reason)
+
+		switch decision.Action {
+		case conflict.ActionSkip:
+			o.skip(filePath, "conflict: "+decision.Reason)
+			return fmt.Errorf("%w at %s: %s", ErrConflictSkipped, destinationPath, decision.Reason)
+		case conflict.ActionRename:
+			destinationPath = conflict.SuffixedPath(destinationPath)
+		case conflict.ActionReplace:
+			previous, _ = o.journal.Placed(destinationPath)
+			// Set the existing file aside until the new one is in place
+			replaced = destinationPath + ".flick-replaced"
+			if err := os.Rename(destinationPath, replaced); err != nil {
+				log.Printf("Could not set aside existing file %s: %v", destinationPath, err)
+				return err
 			}
+		}
+	}
 
-			log.Printf("Calculated final path: %s", destinationPath)
+	mode, err := transfer.Transfer(filePath, destinationPath, transferMode)
+	if err != nil {
+		log.Printf("Could not %s to final path. Error: %s", transferMode, err)
+		if replaced != "" {
+			os.Rename(replaced, destinationPath)
+		}
+		return err
+	}
+	if replaced != "" {
+		os.Remove(replaced)
+		// They belong to the old release, and would keep the new ones from being placed
+		for _, c := range previous.Companions {
+			if err := os.Remove(c.Destination); err != nil && !os.IsNotExist(err) {
+				log.Printf("Could not remove companion %s of the replaced file: %v", c.Destination, err)
+			}
 		}
+	}
 
-		log.Println("Watcher channel closed. Organizer stopping.")
-	}()
+	log.Printf("Calculated final path: %s", destinationPath)
+
+	op, err := o.journal.Record(journal.Operation{
+		BatchID:     o.batchID,
+		Source:      filePath,
+		Destination: destinationPath,
+		Mode:        mode,
+		MediaInfo:   *mediaInfo,
+		Companions:  o.placeCompanions(filePath, destinationPath, mode, folder),
+		Replaces:    previous.ID,
+	})
+	if err != nil {
+		log.Printf("Could not record operation in journal: %v", err)
+		return err
+	}
+	log.Printf("Recorded operation %s (batch %s)", op.ID, op.BatchID)
+	return nil
+}
+
+// placeCompanions moves the subtitles, NFOs and artwork of a file next to its
+// destination, named after it. A companion that can't be placed is left behind
+// without failing the file itself.
+func (o *Organizer) placeCompanions(filePath, destinationPath string, mode transfer.Mode, folder *folderGroup) []journal.Companion {
+	files := companion.Plan(filePath, destinationPath, o.Config.VideoExtensions)
+	if dir := folder.claim(); dir != "" {
+		// Once per download folder, into the folder of its first placed file
+		for _, file := range companion.FolderFiles(dir, filepath.Dir(destinationPath)) {
+			if !slices.ContainsFunc(files, func(f companion.File) bool { return f.Source == file.Source }) {
+				files = append(files, file)
+			}
+		}
+	}
+
+	var placed []journal.Companion
+	for _, file := range files {
+		if _, err := os.Lstat(file.Destination); err == nil {
+			log.Printf("Companion %s already exists, leaving %s in place", file.Destination, file.Source)
+			continue
+		}
+		used, err := transfer.Transfer(file.Source, file.Destination, mode)
+		if err != nil {
+			log.Printf("Could not %s companion %s: %v", mode, file.Source, err)
+			continue
+		}
+		log.Printf("Companion %s placed as %s", file.Source, file.Destination)
+		placed = append(placed, journal.Companion{Source: file.Source, Destination: file.Destination, Mode: used})
+	}
+	return placed
+}
+
+func (o *Organizer) decideConflict(filePath, destinationPath string, mediaInfo *models.MediaInfo) conflict.Decision {
+	var existing, incoming conflict.Quality
+	if o.Config.ConflictPolicy == conflict.Upgrade {
+		existing, _ = conflict.Probe(destinationPath)
+		incoming, _ = conflict.Probe(filePath)
+		withRelease(&incoming, mediaInfo.Release)
+		// Library names rarely keep the release details, the journal does
+		if op, ok := o.journal.Placed(destinationPath); ok {
+			withRelease(&existing, op.MediaInfo.Release)
+		}
+	}
+	return conflict.Decide(o.Config.ConflictPolicy, existing, incoming)
+}
+
+// withRelease completes a probed quality with what the release name told us
+func withRelease(q *conflict.Quality, release models.Release) {
+	if q.Height == 0 {
+		q.Height, _ = strconv.Atoi(strings.TrimSuffix(release.Resolution, "p"))
+	}
+	q.Source = conflict.SourceRank(release.Source)
+	q.Revision = 1
+	if release.Proper || release.Repack {
+		q.Revision = 2
+	}
 }
 
 // Equivalent to a dry run
 func (o *Organizer) GetDestinationPath(filePath string) string {
+	destinationPath, _ := o.resolve(filePath)
+	return destinationPath
+}
+
+// resolve returns the destination path together with the media info it was built from
+func (o *Organizer) resolve(filePath string) (string, *models.MediaInfo) {
+	mediaInfo, err := o.identify(context.Background(), filePath, filePath)
+	if err != nil {
+		fmt.Println(err)
+		return "", nil
+	}
+	return o.destinationFor(filePath, mediaInfo, o.profileFor(filePath)), mediaInfo
+}
+
+// identify parses the file name and looks it up on TMDb
+func (o *Organizer) identify(ctx context.Context, filePath, foundAt string) (*models.MediaInfo, error) {
+	parsed, err := o.parseFile(filePath, foundAt)
+	if err != nil {
+		return nil, err
+	}
+	return o.lookup(ctx, parsed)
+}
+
+// parseFile parses the file at filePath by the path it was found at, see organize
+func (o *Organizer) parseFile(filePath, foundAt string) (*models.MediaInfo, error) {
 	info, err := os.Stat(filePath)
 	if err != nil {
-		return ""
+		return nil, fmt.Errorf("%w: %v", ErrIO, err)
 	}
 	if info.IsDir() {
-		return ""
+		return nil, fmt.Errorf("%w: %s is a directory", ErrIO, filePath)
 	}
 
-	fileName := filepath.Base(filePath)
-	cleanFileName := o.parser.ParseNormalized(fileName)
-	mediaInfo, err := o.finder.GetMediaInfo(*cleanFileName.MediaInfo)
+	cleanFileName := o.parser.ParsePath(o.relativePath(foundAt))
+	if len(cleanFileName.Errors) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrParse, cleanFileName.Errors[0])
+	}
+	if folder, ok := cleanFileName.Sources["Title"]; ok && folder != filepath.Base(foundAt) {
+		log.Printf("Title of %s taken from its folder %s", filePath, folder)
+	}
+	if crc := cleanFileName.MediaInfo.CRC32; o.Config.VerifyChecksums && crc != "" {
+		if err := verifyCRC32(filePath, crc); err != nil {
+			return nil, err
+		}
+	}
+	return cleanFileName.MediaInfo, nil
+}
+
+// relativePath is the path of a file below its watch folder, whose folders are
+// parsed too, e.g. "Show.S02.1080p/E05.mkv". Folders above the watch folder say
+// nothing about the file, nor do those of a file outside it, which is just its name.
+func (o *Organizer) relativePath(filePath string) string {
+	rel, err := filepath.Rel(o.profileFor(filePath).Watcher.Config.Path, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(filePath)
+	}
+	return rel
+}
+
+func (o *Organizer) lookup(ctx context.Context, parsed *models.MediaInfo) (*models.MediaInfo, error) {
+	mediaInfo, err := o.finder.GetMediaInfo(ctx, *parsed)
 	if err != nil {
-		fmt.Println(err)
-		return ""
+		return nil, err
 	}
 	fmt.Println(mediaInfo.Title)
 	fmt.Println(mediaInfo.Year)
 	fmt.Printf("Accuracy: %v\n", mediaInfo.Accuracy)
 
+	return mediaInfo, nil
+}
+
+func (o *Organizer) destinationFor(filePath string, mediaInfo *models.MediaInfo, profile *Profile) string {
+	fileName := filepath.Base(filePath)
+	if mediaInfo.IsSeries && mediaInfo.Episode == 0 {
+		log.Printf("No episode number found for series file: %s", fileName)
+		return ""
+	}
+
 	var destinationPath string
 	if mediaInfo.IsSeries {
-		destinationPath = o.seriesDir + mediaInfo.Title
+		// E.G. /base/series/directory/Dark/Season 1/Dark - S01E03.mkv
+		destinationPath = filepath.Join(profile.SeriesDir, profile.SeriesTemplate.Render(mediaInfo, filepath.Ext(fileName)))
 	} else {
-		folderName := mediaInfo.Title + "(" + strconv.Itoa(mediaInfo.Year) + ")"
-		// E.G. /base/movies/directory/Titatnic(1999)/Titanic(1999).mkv
-		destinationPath = filepath.Join(o.moviesDir, folderName, folderName+filepath.Ext(fileName))
+		// E.G. /base/movies/directory/Titanic (1997)/Titanic (1997).mkv
+		destinationPath = filepath.Join(profile.MoviesDir, profile.MovieTemplate.Render(mediaInfo, filepath.Ext(fileName)))
 	}
 
 	return destinationPath
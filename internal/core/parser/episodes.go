@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// The first episode of a file, S01E01 or 1x01
+	episodeStart = regexp.MustCompile(`(?:^|[^a-z0-9])(?:s(\d{1,3})[ ._-]?e(\d{1,4})|(\d{1,2})x(\d{1,4}))`)
+	// What may follow it: a range end (-E03, -03, -1x03) or another episode (E02)
+	episodeRange = regexp.MustCompile(`^[ ._]?-[ ._]?(?:e|\d{1,2}x)?(\d{1,4})`)
+	episodeNext  = regexp.MustCompile(`^[ ._-]?e(\d{1,4})`)
+)
+
+// maxEpisodesPerFile keeps a range like "S01E01-2020" from turning into thousands of episodes
+const maxEpisodesPerFile = 20
+
+// extractEpisodes finds every episode of a multi-episode name, without extension:
+// S01E01E02, S01E01-E03, S01E01-03, 1x01-02, 1x01-1x02. Ranges are expanded.
+// It returns the season and the sorted episodes, or no episodes if the name has none.
+func extractEpisodes(name string) (int, []int) {
+	lower := []byte(strings.ToLower(name))
+	loc := episodeStart.FindSubmatchIndex(lower)
+	if loc == nil {
+		return 0, nil
+	}
+
+	seasonGroup, episodeGroup := 2, 4
+	if loc[seasonGroup] < 0 {
+		seasonGroup, episodeGroup = 6, 8
+	}
+	season, _ := strconv.Atoi(string(lower[loc[seasonGroup]:loc[seasonGroup+1]]))
+	first, _ := strconv.Atoi(string(lower[loc[episodeGroup]:loc[episodeGroup+1]]))
+	episodes := []int{first}
+
+	rest := lower[loc[1]:]
+	last := first
+	for {
+		if m := episodeRange.FindSubmatchIndex(rest); m != nil && endsNumber(rest, m[1]) {
+			end, _ := strconv.Atoi(string(rest[m[2]:m[3]]))
+			if end <= last || end-first >= maxEpisodesPerFile {
+				break
+			}
+			for e := last + 1; e <= end; e++ {
+				episodes = append(episodes, e)
+			}
+			last, rest = end, rest[m[1]:]
+			continue
+		}
+		if m := episodeNext.FindSubmatchIndex(rest); m != nil && endsNumber(rest, m[1]) {
+			next, _ := strconv.Atoi(string(rest[m[2]:m[3]]))
+			if next <= last || next-first >= maxEpisodesPerFile {
+				break
+			}
+			episodes = append(episodes, next)
+			last, rest = next, rest[m[1]:]
+			continue
+		}
+		break
+	}
+
+	slices.Sort(episodes)
+	return season, slices.Compact(episodes)
+}
+
+// endsNumber checks that a matched episode number isn't the start of
+// something else, e.g. the "720" of "-720p"
+func endsNumber(s []byte, end int) bool {
+	if end >= len(s) {
+		return true
+	}
+	c := s[end]
+	return !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9')
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// "[Group] " at the start of the name
+	fansubGroup = regexp.MustCompile(`^\s*\[[^\]]*\]\s*`)
+	// "[ABCD1234]", the CRC32 of the file
+	crcTag = regexp.MustCompile(`[\[(]([0-9A-Fa-f]{8})[\])]`)
+	// "Title - 12", a bare episode number after a spaced dash, maybe a "v2" fix
+	absoluteEpisode = regexp.MustCompile(`^(.+?)\s+-\s+(\d{1,4})(?:v\d)?(?:\s|$|[\[(])`)
+)
+
+// splitFansub takes the conventions of anime fansub names out of a raw name,
+// e.g. "[SubsPlease] Frieren - 12 (1080p) [ABCD1234]". It returns the name left
+// to tokenize, the absolute episode after the dash and the CRC32 tag, if any.
+// The group and the tags would otherwise be read as part of the title.
+func (p *MediaParser) splitFansub(name string) (string, int, string) {
+	var crc string
+	if m := crcTag.FindStringSubmatch(name); m != nil {
+		crc = strings.ToUpper(m[1])
+	}
+	group := fansubGroup.FindString(name)
+	rest := crcTag.ReplaceAllString(strings.TrimPrefix(name, group), " ")
+
+	// Only a fansub names episodes this way, elsewhere "Rocky - 4" is a sequel.
+	// A file under a season folder gets its number read by ParsePath instead.
+	if group == "" && crc == "" {
+		return rest, 0, crc
+	}
+	title, episode, ok := dashEpisode(rest)
+	if !ok {
+		return rest, 0, crc
+	}
+	// What follows the episode is release info, already read from the raw name
+	return title, episode, crc
+}
+
+// dashEpisode reads "Title - 12" as the title and the episode number after the dash
+func dashEpisode(name string) (string, int, bool) {
+	m := absoluteEpisode.FindStringSubmatch(name)
+	if m == nil {
+		return "", 0, false
+	}
+	episode, _ := strconv.Atoi(m[2])
+	if episode == 0 {
+		return "", 0, false
+	}
+	return m[1], episode, true
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	models "github.com/alejandro-bustamante/flick/internal/models"
@@ -53,8 +54,11 @@ func (p *MediaParser) Parse(filename string) *models.ParseResult {
 
 	p.logger.Debug("Parsing file: %s", filename)
 
+	name, absolute, crc := p.splitFansub(filename)
+	name, part := p.splitPart(name)
+
 	// Fase 1: Tokenización (interna)
-	tokens := p.tokenize(filename)
+	tokens := p.tokenize(name)
 	p.logger.Debug("Tokens: %v", tokens)
 
 	// Fase 2: Limpieza (interna)
@@ -62,8 +66,15 @@ func (p *MediaParser) Parse(filename string) *models.ParseResult {
 	p.logger.Debug("Clean tokens: %v", cleanTokens)
 
 	// Fase 3: Extracción (interna)
-	info := p.extract(cleanTokens)
+	info := p.extract(name, cleanTokens)
 	info.OriginalName = filename
+	info.Release = extractRelease(filename)
+	info.CRC32 = crc
+	p.addEpisodes(info, filename)
+	p.addAbsolute(info, absolute)
+	if !info.IsSeries {
+		info.Part = part
+	}
 
 	result.MediaInfo = info
 
@@ -78,8 +89,24 @@ func (p *MediaParser) ParseNormalized(filename string) *models.ParseResult {
 	ext := filepath.Ext(filename)
 	filename = strings.Trim(filename, ext)
 
-	// Stage 1: Tokenization (interna)
-	tokens := p.tokenize(filename)
+	info := p.parseNormalizedName(filename)
+
+	// Stage 5: Validation
+	if err := p.validateTitle(info.Title); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+
+	result.MediaInfo = info
+	return result
+}
+
+// parseNormalizedName runs the stages of ParseNormalized up to the extraction
+// on a name without extension.
+func (p *MediaParser) parseNormalizedName(filename string) *models.MediaInfo {
+	// Stage 1: Tokenization (interna), without fansub groups and tags
+	name, absolute, crc := p.splitFansub(filename)
+	name, part := p.splitPart(name)
+	tokens := p.tokenize(name)
 	p.logger.Debug("Tokens: %v", tokens)
 
 	// Stage 2: Normalization (interna)
@@ -91,11 +118,17 @@ func (p *MediaParser) ParseNormalized(filename string) *models.ParseResult {
 	p.logger.Debug("Clean tokens: %v", cleanTokens)
 
 	// Stage 4: Extraction (interna)
-	info := p.extract(cleanTokens)
+	info := p.extract(name, cleanTokens)
 	info.OriginalName = filename
-
-	result.MediaInfo = info
-	return result
+	// From the raw name, tokens have lost the punctuation of "WEB-DL" or "DDP5.1"
+	info.Release = extractRelease(filename)
+	info.CRC32 = crc
+	p.addEpisodes(info, filename)
+	p.addAbsolute(info, absolute)
+	if !info.IsSeries {
+		info.Part = part
+	}
+	return info
 }
 
 func (p *MediaParser) tokenize(input string) []string {
@@ -123,21 +156,29 @@ func (p *MediaParser) clean(tokens []string) []string {
 }
 
 // --- Lógica de Extractor ---
-func (p *MediaParser) extract(tokens []string) *models.MediaInfo {
+// extract reads title, year, season and episode from the tokens of name
+func (p *MediaParser) extract(name string, tokens []string) *models.MediaInfo {
 	info := &models.MediaInfo{}
 	var titleTokens []string
 	var trailing []string
 
 	lastRelevantIdx := -1
 
+	// An air date (daily shows) takes the place of season and episode
+	dateStart, dateEnd, airDate := p.extractAirDate(name, tokens)
+
 	// Buscar año o temporada/episodio
 	for i, token := range tokens {
+		if dateStart >= 0 && i >= dateStart && i < dateEnd {
+			continue // The year of the air date is not the year of the show
+		}
 		if year := p.extractYear(token); year > 0 {
 			info.Year = year
 			lastRelevantIdx = i
 		}
 
-		if season, episode := p.extractSeasonEpisode(token); season > 0 {
+		// Only the first match, in "S01E01-E03" the "E03" would win otherwise
+		if season, episode, ok := p.extractSeasonEpisode(token); ok && !info.IsSeries {
 			info.Season = season
 			info.Episode = episode
 			lastRelevantIdx = i
@@ -145,12 +186,24 @@ func (p *MediaParser) extract(tokens []string) *models.MediaInfo {
 		}
 	}
 
-	// Dividir título del resto
+	// Where the title ends and the rest begins
+	titleEnd, restStart := len(tokens), len(tokens)
 	if lastRelevantIdx >= 0 {
-		titleTokens = tokens[:lastRelevantIdx]
-		trailing = tokens[lastRelevantIdx+1:]
-	} else {
-		titleTokens = tokens
+		titleEnd, restStart = lastRelevantIdx, lastRelevantIdx+1
+	}
+
+	if dateStart >= 0 && !info.IsSeries {
+		info.IsSeries = true
+		info.AirDate = airDate
+		if titleEnd > dateStart {
+			titleEnd, restStart = dateStart, dateEnd
+		}
+	}
+
+	// Dividir título del resto
+	titleTokens = tokens[:titleEnd]
+	if restStart < len(tokens) {
+		trailing = tokens[restStart:]
 	}
 
 	info.Title = p.buildTitle(titleTokens)
@@ -170,7 +223,64 @@ func (p *MediaParser) extractYear(token string) int {
 	return 0
 }
 
-func (p *MediaParser) extractSeasonEpisode(token string) (int, int) {
+// airDateYear reads the year of an air date. Unlike extractYear it is not capped
+// by year_range, which lags behind for release years, but by what can have aired
+// already: a daily show recorded today must not turn into a movie.
+func (p *MediaParser) airDateYear(token string) int {
+	if len(token) != 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(token)
+	if err != nil || year < p.yearRange[0] || year > time.Now().Year()+1 {
+		return 0
+	}
+	return year
+}
+
+// A date with two digit month and day. RE2 has no backreferences, the two
+// separators are compared by extractAirDate.
+var airDatePattern = regexp.MustCompile(`(?:^|[^0-9])(?:(\d{4})([.\-_ ])(\d{2})([.\-_ ])(\d{2})|(\d{2})([.\-_ ])(\d{2})([.\-_ ])(\d{4}))(?:$|[^0-9])`)
+
+// extractAirDate looks for a date in the name, YYYY-MM-DD or DD-MM-YYYY with
+// any one separator. It has to be read before tokenizing, as tokens can't tell
+// "2019.05.01" from "2019 1080p 5.1". It returns the range [start, end) of its
+// three tokens and the date as YYYY-MM-DD, or start -1 if there is none.
+func (p *MediaParser) extractAirDate(name string, tokens []string) (int, int, string) {
+	for _, m := range airDatePattern.FindAllStringSubmatch(name, -1) {
+		var year, month, day string
+		switch {
+		case m[1] != "" && m[2] == m[4]:
+			year, month, day = m[1], m[3], m[5]
+		case m[6] != "" && m[7] == m[9]:
+			day, month, year = m[6], m[8], m[10]
+		default:
+			continue // Mixed separators, e.g. "2019.05-01"
+		}
+		if p.airDateYear(year) == 0 {
+			continue
+		}
+		date := year + "-" + month + "-" + day
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			continue
+		}
+		// The same three numbers among the tokens, in the order of the name
+		first, second, third := year, month, day
+		if m[1] == "" {
+			first, third = day, year
+		}
+		for i := 0; i+2 < len(tokens); i++ {
+			if tokens[i] == first && tokens[i+1] == second && tokens[i+2] == third {
+				return i, i + 3, date
+			}
+		}
+	}
+	return -1, -1, ""
+}
+
+// extractSeasonEpisode reads S01E05 and the like from a token. Season 0 holds the
+// specials. For an episode alone, e.g. "E05" in a season pack, the season is
+// models.UnknownSeason, to be taken from the folder.
+func (p *MediaParser) extractSeasonEpisode(token string) (int, int, bool) {
 	lower := strings.ToLower(token)
 
 	seasonEpisodePatterns := []*regexp.Regexp{
@@ -185,8 +295,8 @@ func (p *MediaParser) extractSeasonEpisode(token string) (int, int) {
 		if len(matches) == 3 {
 			season, err1 := strconv.Atoi(matches[1])
 			episode, err2 := strconv.Atoi(matches[2])
-			if err1 == nil && err2 == nil && season > 0 && episode > 0 {
-				return season, episode
+			if err1 == nil && err2 == nil && episode > 0 {
+				return season, episode, true
 			}
 		}
 	}
@@ -201,12 +311,35 @@ func (p *MediaParser) extractSeasonEpisode(token string) (int, int) {
 		if len(matches) == 2 {
 			episode, err := strconv.Atoi(matches[1])
 			if err == nil && episode > 0 {
-				return 1, episode
+				return models.UnknownSeason, episode, true
 			}
 		}
 	}
 
-	return 0, 0
+	return 0, 0, false
+}
+
+// addEpisodes fills Episodes, with every episode of a multi-episode file when
+// the raw name has one of those syntaxes, which tokenizing breaks apart.
+func (p *MediaParser) addEpisodes(info *models.MediaInfo, filename string) {
+	if !info.IsSeries {
+		return
+	}
+	if season, episodes := extractEpisodes(filename); season == info.Season && len(episodes) > 0 && episodes[0] == info.Episode {
+		info.Episodes = episodes
+		return
+	}
+	info.Episodes = []int{info.Episode}
+}
+
+// addAbsolute marks a file numbered only by its absolute episode as a series.
+// The season is left at 0, which season it falls in is up to the finder.
+func (p *MediaParser) addAbsolute(info *models.MediaInfo, absolute int) {
+	if absolute == 0 || info.IsSeries {
+		return
+	}
+	info.IsSeries = true
+	info.AbsoluteEpisode = absolute
 }
 
 func (p *MediaParser) buildTitle(tokens []string) string {
@@ -232,7 +365,7 @@ func (p *MediaParser) validate(info *models.MediaInfo) error {
 		return err
 	}
 
-	if info.Season == 0 {
+	if info.Season == models.UnknownSeason {
 		return fmt.Errorf("TV show must have season number")
 	}
 
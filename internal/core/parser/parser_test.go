@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/alejandro-bustamante/flick/internal/config"
+	"github.com/alejandro-bustamante/flick/internal/utils"
+)
+
+// newTestParser builds the parser the way the daemon does, from the repo's patterns.toml
+func newTestParser(t *testing.T) *MediaParser {
+	t.Helper()
+	data, err := config.LoadData("../../../patterns.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMediaParser(data.Tokenizer.Separators, data.Cleaner.JunkPatterns, data.Extractor.YearRange[:], utils.NewLogger("error"))
+}
+
+func TestAirDate(t *testing.T) {
+	p := newTestParser(t)
+	tests := []struct {
+		name    string
+		airDate string
+		title   string
+	}{
+		{"The.Daily.Show.2026.03.14.mkv", "2026-03-14", "the daily show"},
+		{"Show Name 2019-05-01 Guest.mkv", "2019-05-01", "show name"},
+		{"Show_Name_14_03_2024.mkv", "2024-03-14", "show name"},
+		// Audio channels after the year are not a month and a day
+		{"Inception (2010) [1080p] [BluRay] [5.1] [YTS.MX].mp4", "", "inception"},
+		{"Movie.Name.2019.1080p.5.1.x264.mkv", "", "movie name"},
+		{"Movie.Name.2019.7.1.mkv", "", "movie name"},
+		{"Movie.Name.2019.2.0.mkv", "", "movie name"},
+		{"Movie.Name.2019.AAC2.0.WEB.mkv", "", "movie name"},
+		// Mixed separators are two numbers after a year, not a date
+		{"Movie.Name.2019.05-01.mkv", "", "movie name"},
+	}
+	for _, tt := range tests {
+		info := p.ParseNormalized(tt.name).MediaInfo
+		if info.AirDate != tt.airDate {
+			t.Errorf("%s: air date %q, want %q", tt.name, info.AirDate, tt.airDate)
+		}
+		if info.IsSeries != (tt.airDate != "") {
+			t.Errorf("%s: series %v", tt.name, info.IsSeries)
+		}
+		if info.Title != tt.title {
+			t.Errorf("%s: title %q, want %q", tt.name, info.Title, tt.title)
+		}
+	}
+}
+
+func TestAbsoluteEpisode(t *testing.T) {
+	p := newTestParser(t)
+	tests := []struct {
+		name     string
+		absolute int
+		title    string
+		year     int
+	}{
+		{"[SubsPlease] Frieren - 12 (1080p) [ABCD1234].mkv", 12, "frieren", 0},
+		{"Frieren - 12 (1080p) [ABCD1234].mkv", 12, "frieren", 0},
+		{"[Group] One Piece - 1071v2 [1080p].mkv", 1071, "one piece", 0},
+		// Without a group or a CRC a number after a dash is part of the title
+		{"Rocky - 4.mkv", 0, "rocky 4", 0},
+		{"Alien - 3.avi", 0, "alien 3", 0},
+		{"Mission Impossible - 2 (2000).mkv", 0, "mission impossible 2", 2000},
+		{"Blade Runner - 2049.mkv", 0, "blade runner 2049", 0},
+	}
+	for _, tt := range tests {
+		info := p.ParseNormalized(tt.name).MediaInfo
+		if info.AbsoluteEpisode != tt.absolute || info.IsSeries != (tt.absolute > 0) {
+			t.Errorf("%s: absolute %d, series %v, want %d", tt.name, info.AbsoluteEpisode, info.IsSeries, tt.absolute)
+		}
+		if info.Title != tt.title || info.Year != tt.year {
+			t.Errorf("%s: %q (%d), want %q (%d)", tt.name, info.Title, info.Year, tt.title, tt.year)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	p := newTestParser(t)
+	tests := []struct {
+		path    string
+		title   string
+		series  bool
+		season  int
+		episode int
+		sources map[string]string
+	}{
+		{
+			path: "Show/Season 2/Show - 05.mkv", title: "show", series: true, season: 2, episode: 5,
+			sources: map[string]string{"Season": "Season 2", "Episode": "Show - 05.mkv"},
+		},
+		{
+			path: "Show/Season 2/[Group] Show - 05 [ABCD1234].mkv", title: "show", series: true, season: 2, episode: 5,
+			sources: map[string]string{"Season": "Season 2", "Episode": "[Group] Show - 05 [ABCD1234].mkv"},
+		},
+		{
+			path: "Show S03/03.mkv", title: "show", series: true, season: 3, episode: 3,
+			sources: map[string]string{"Season": "Show S03", "Title": "Show S03"},
+		},
+		{
+			path: "Show (2019)/Season 1/Show - S01E04.mkv", title: "show", series: true, season: 1, episode: 4,
+			sources: map[string]string{"Season": "Show - S01E04.mkv", "Year": "Show (2019)"},
+		},
+		// Outside a season folder the dash is part of a movie's title
+		{path: "Movies/Rocky - 4.mkv", title: "rocky 4"},
+	}
+	for _, tt := range tests {
+		result := p.ParsePath(tt.path)
+		info := result.MediaInfo
+		if info.Title != tt.title || info.IsSeries != tt.series || info.Season != tt.season || info.Episode != tt.episode {
+			t.Errorf("%s: %q series %v S%02dE%02d, want %q series %v S%02dE%02d", tt.path,
+				info.Title, info.IsSeries, info.Season, info.Episode, tt.title, tt.series, tt.season, tt.episode)
+		}
+		if tt.series && info.AbsoluteEpisode != 0 {
+			t.Errorf("%s: absolute episode %d left over", tt.path, info.AbsoluteEpisode)
+		}
+		for field, want := range tt.sources {
+			if got := result.Sources[field]; got != want {
+				t.Errorf("%s: %s from %q, want %q", tt.path, field, got, want)
+			}
+		}
+	}
+}
+
+func TestParsePathReleaseGroup(t *testing.T) {
+	p := newTestParser(t)
+	tests := []struct{ path, group string }{
+		// Title from the folder, the name's "-mn2019" is no group
+		{"Movie.Name.2019.1080p.BluRay.x264-SPARKS/abc-mn2019.mkv", "SPARKS"},
+		// Title from the name, which has no group of its own
+		{"Show.S01.1080p.WEB-DL-NTb/Show.S01E01.mkv", "NTb"},
+		{"Show.S01.1080p.WEB-DL-NTb/Show.S01E01.1080p.WEB-DL-FLUX.mkv", "FLUX"},
+	}
+	for _, tt := range tests {
+		if got := p.ParsePath(tt.path).MediaInfo.ReleaseGroup; got != tt.group {
+			t.Errorf("%s: group %q, want %q", tt.path, got, tt.group)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// One file of a movie split in several, e.g. "Movie.1999.CD1" or "Movie.1999.Part.2"
+	partPattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(cd|dis[ck]|part|pt)[ ._-]?(\d{1,2})(?:$|[^a-z0-9])`)
+	// A folder holding one of the parts, e.g. "Movie.1999/CD1/movie.avi"
+	partFolder = regexp.MustCompile(`(?i)^\s*(cd|dis[ck]|part|pt)[ ._-]?(\d{1,2})\s*$`)
+)
+
+// splitPart takes the part of a multi-part movie out of a raw name. It returns
+// the name without it and the part as Plex and Jellyfin stack them, e.g. "cd1".
+// "Part" and "Pt" only count after the year, before it they are the title, as
+// in "Harry.Potter.and.the.Deathly.Hallows.Part.1.2010".
+func (p *MediaParser) splitPart(name string) (string, string) {
+	loc := partPattern.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return name, ""
+	}
+	kind := strings.ToLower(name[loc[2]:loc[3]])
+	number, _ := strconv.Atoi(name[loc[4]:loc[5]])
+	if number == 0 {
+		return name, ""
+	}
+	if kind == "part" || kind == "pt" {
+		hasYear := false
+		for _, token := range p.tokenize(name[:loc[0]]) {
+			if p.extractYear(token) > 0 {
+				hasYear = true
+			}
+		}
+		if !hasYear {
+			return name, ""
+		}
+	}
+	return name[:loc[0]] + " " + name[loc[1]:], partName(kind, number)
+}
+
+// folderPart reads the part from a folder named only after it, e.g. "CD1" or
+// "Disc 2". Unlike in a name, there is no title for "Part" to belong to.
+func folderPart(name string) string {
+	m := partFolder.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	number, _ := strconv.Atoi(m[2])
+	if number == 0 {
+		return ""
+	}
+	return partName(strings.ToLower(m[1]), number)
+}
+
+func partName(kind string, number int) string {
+	if kind == "disk" {
+		kind = "disc"
+	}
+	return kind + strconv.Itoa(number)
+}
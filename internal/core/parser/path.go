@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	models "github.com/alejandro-bustamante/flick/internal/models"
+)
+
+// A file named only by its episode number, e.g. "03.mkv" or "03 - Pilot.mkv"
+var bareEpisode = regexp.MustCompile(`^\s*(\d{1,3})(?:$|[ ._-])`)
+
+// component is the parse of one element of a path
+type component struct {
+	name string
+	info *models.MediaInfo
+	// The name has a season, episode, air date or year, so it says what it is
+	anchored bool
+}
+
+// ParsePath parses a path relative to the watch folder, e.g.
+// "Movie.Name.2019.1080p.BluRay/abc-mn2019.mkv" or "Show S03/03.mkv". The file
+// name is parsed as ParseNormalized does, and its folders, nearest first, fill
+// in what it lacks:
+//
+//   - Title: from the file name if it has a season, episode, air date or year.
+//     Otherwise from the nearest folder that has one of those, as obfuscated or
+//     bare file names say nothing. Failing both, the file name, then the nearest
+//     folder with any title.
+//   - Year: from the component the title came from, else from a folder with the
+//     same title, e.g. "Show (2019)/Season 1/Show - S01E01.mkv".
+//   - Season: a file with an episode alone takes it from the nearest folder
+//     naming one ("Show S03", "Season 3", "Specials"), else it is season 1.
+//     Under a season folder a bare number ("03", "Show - 05") or a fansub's
+//     absolute number is the episode within that season.
+//   - Part: from the file name, else from the folder it sits in when that is
+//     named after a part alone, e.g. "Movie.1999/CD1/movie.avi".
+//   - Release attributes: from the file name, each missing one from the nearest
+//     folder that has it. When the title comes from a folder, the group of the
+//     file name is ignored, as an obfuscated name like "abc-mn2019" only looks
+//     like it ends in one.
+//
+// Sources in the result tells which component each field was read from.
+func (p *MediaParser) ParsePath(relPath string) *models.ParseResult {
+	result := &models.ParseResult{Sources: make(map[string]string)}
+
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(relPath)), "/")
+	fileName := parts[len(parts)-1]
+	p.logger.Debug("Parsing path: %s", relPath)
+
+	file := p.ParseNormalized(fileName).MediaInfo
+	info := *file
+	fileComponent := component{name: fileName, info: file, anchored: isAnchored(file)}
+	var folders []component
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] == "." || parts[i] == ".." || parts[i] == "" {
+			continue
+		}
+		folder := p.parseFolder(parts[i])
+		folders = append(folders, component{name: parts[i], info: folder, anchored: isAnchored(folder)})
+	}
+
+	var seasonFolder *component
+	for i := range folders {
+		if folders[i].info.IsSeries && folders[i].info.Season >= 0 {
+			seasonFolder = &folders[i]
+			break
+		}
+	}
+
+	// A number alone means an episode only inside a season folder
+	inSeason := func(episode int) {
+		info.IsSeries = true
+		info.Season = models.UnknownSeason
+		info.Episode = episode
+		info.Episodes = []int{episode}
+		info.AbsoluteEpisode = 0
+		result.Sources["IsSeries"] = seasonFolder.name
+		result.Sources["Episode"] = fileName
+	}
+	bare := 0
+	if m := bareEpisode.FindStringSubmatch(fileName); m != nil {
+		bare, _ = strconv.Atoi(m[1])
+	}
+	title, dashed, isDashed := dashEpisode(strings.TrimSuffix(fileName, filepath.Ext(fileName)))
+	switch {
+	case seasonFolder != nil && info.IsSeries && info.Episode == 0 && info.AbsoluteEpisode > 0:
+		// "Season 2/[Group] Show - 05.mkv", counted within the season
+		inSeason(info.AbsoluteEpisode)
+	case seasonFolder != nil && !info.IsSeries && bare > 0:
+		// "Show S03/03.mkv"
+		inSeason(bare)
+		// Whatever follows the number is the episode title, not the show's
+		fileComponent.info = &models.MediaInfo{}
+	case seasonFolder != nil && !info.IsSeries && isDashed:
+		// "Show/Season 2/Show - 05.mkv"
+		inSeason(dashed)
+		fileComponent.info = p.parseNormalizedName(title)
+	case info.IsSeries:
+		result.Sources["IsSeries"] = fileName
+		result.Sources["Season"] = fileName
+		if info.Episode > 0 {
+			result.Sources["Episode"] = fileName
+		}
+		if info.AirDate != "" {
+			result.Sources["AirDate"] = fileName
+		}
+		if info.AbsoluteEpisode > 0 {
+			result.Sources["AbsoluteEpisode"] = fileName
+		}
+	}
+
+	titleFrom := chooseTitle(fileComponent, folders)
+	info.Title = titleFrom.info.Title
+	info.Year = titleFrom.info.Year
+	result.Sources["Title"] = titleFrom.name
+	if info.Year > 0 {
+		result.Sources["Year"] = titleFrom.name
+	} else if folder, ok := p.sameTitleYear(info.Title, folders); ok {
+		info.Year = folder.info.Year
+		result.Sources["Year"] = folder.name
+	}
+
+	if info.Part != "" {
+		result.Sources["Part"] = fileName
+	} else if len(folders) > 0 && !info.IsSeries {
+		if part := folderPart(folders[0].name); part != "" {
+			info.Part = part
+			result.Sources["Part"] = folders[0].name
+		}
+	}
+
+	if info.IsSeries && info.Season == models.UnknownSeason {
+		if seasonFolder != nil {
+			info.Season = seasonFolder.info.Season
+			result.Sources["Season"] = seasonFolder.name
+		} else {
+			info.Season = 1 // A show with a single season
+			delete(result.Sources, "Season")
+		}
+	}
+
+	if titleFrom.name != fileName {
+		// An obfuscated name like "abc-mn2019" ends in what looks like a group
+		info.ReleaseGroup = ""
+	}
+	p.mergeRelease(&info.Release, fileName, folders, result.Sources)
+
+	if err := p.validateTitle(info.Title); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	result.MediaInfo = &info
+	return result
+}
+
+// parseFolder parses a folder name. Unlike a file name it has no extension, and
+// may name a season alone, e.g. "Show.S02.1080p" or "Season 02".
+func (p *MediaParser) parseFolder(name string) *models.MediaInfo {
+	season, start, ok := seasonFromFolder(name)
+	if !ok {
+		return p.parseNormalizedName(name)
+	}
+
+	var info *models.MediaInfo
+	if title := strings.TrimSpace(name[:start]); title != "" {
+		info = p.parseNormalizedName(title)
+	} else {
+		info = &models.MediaInfo{}
+	}
+	info.IsSeries = true
+	info.Season = season
+	info.OriginalName = name
+	info.Release = extractRelease(name)
+	return info
+}
+
+func isAnchored(info *models.MediaInfo) bool {
+	return info.Year > 0 || info.IsSeries
+}
+
+// chooseTitle picks the component the title comes from, see ParsePath.
+func chooseTitle(file component, folders []component) component {
+	if file.anchored && file.info.Title != "" {
+		return file
+	}
+	for _, folder := range folders {
+		if folder.anchored && folder.info.Title != "" {
+			return folder
+		}
+	}
+	if file.info.Title != "" {
+		return file
+	}
+	for _, folder := range folders {
+		if folder.info.Title != "" {
+			return folder
+		}
+	}
+	return file
+}
+
+func (p *MediaParser) sameTitleYear(title string, folders []component) (component, bool) {
+	normalized := p.NormalizeForComparison(title)
+	for _, folder := range folders {
+		if folder.info.Year > 0 && p.NormalizeForComparison(folder.info.Title) == normalized {
+			return folder, true
+		}
+	}
+	return component{}, false
+}
+
+// mergeRelease fills the release attributes the file name lacks from the
+// nearest folder that has them, and records where each one came from.
+func (p *MediaParser) mergeRelease(release *models.Release, fileName string, folders []component, sources map[string]string) {
+	text := []struct {
+		field string
+		value func(*models.Release) *string
+	}{
+		{"Resolution", func(r *models.Release) *string { return &r.Resolution }},
+		{"Source", func(r *models.Release) *string { return &r.Source }},
+		{"VideoCodec", func(r *models.Release) *string { return &r.VideoCodec }},
+		{"AudioCodec", func(r *models.Release) *string { return &r.AudioCodec }},
+		{"AudioChannels", func(r *models.Release) *string { return &r.AudioChannels }},
+		{"ReleaseGroup", func(r *models.Release) *string { return &r.ReleaseGroup }},
+		{"Edition", func(r *models.Release) *string { return &r.Edition }},
+	}
+	for _, t := range text {
+		if *t.value(release) != "" {
+			sources[t.field] = fileName
+			continue
+		}
+		for _, folder := range folders {
+			if value := *t.value(&folder.info.Release); value != "" {
+				*t.value(release) = value
+				sources[t.field] = folder.name
+				break
+			}
+		}
+	}
+
+	if len(release.HDR) > 0 {
+		sources["HDR"] = fileName
+	} else {
+		for _, folder := range folders {
+			if len(folder.info.HDR) > 0 {
+				release.HDR = folder.info.HDR
+				sources["HDR"] = folder.name
+				break
+			}
+		}
+	}
+
+	// A repack or proper folder is that for every file inside it
+	for _, folder := range folders {
+		if folder.info.Repack && !release.Repack {
+			release.Repack = true
+			sources["Repack"] = folder.name
+		}
+		if folder.info.Proper && !release.Proper {
+			release.Proper = true
+			sources["Proper"] = folder.name
+		}
+	}
+	if release.Repack && sources["Repack"] == "" {
+		sources["Repack"] = fileName
+	}
+	if release.Proper && sources["Proper"] == "" {
+		sources["Proper"] = fileName
+	}
+}
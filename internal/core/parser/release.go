@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	models "github.com/alejandro-bustamante/flick/internal/models"
+)
+
+// releasePattern is a name found in release names and how it is written in MediaInfo.
+// Patterns are tried in order, so longer or more specific ones go first.
+type releasePattern struct {
+	re    *regexp.Regexp
+	value string
+}
+
+// word builds a pattern that only matches whole words of a lowercased release
+// name, where words are split by anything that isn't a letter or a digit.
+func word(pattern, value string) releasePattern {
+	return releasePattern{regexp.MustCompile(`(?:^|[^a-z0-9])(?:` + pattern + `)(?:$|[^a-z0-9])`), value}
+}
+
+var (
+	resolutions = []releasePattern{
+		word(`2160[pi]|4k|uhd`, "2160p"),
+		word(`1080[pi]`, "1080p"),
+		word(`720p`, "720p"),
+		word(`576[pi]`, "576p"),
+		word(`480[pi]`, "480p"),
+	}
+	sources = []releasePattern{
+		word(`(?:bd|blu[ .-]?ray)?[ .-]?remux`, "Remux"),
+		word(`blu[ .-]?ray|bdrip|brrip|bd25|bd50`, "BluRay"),
+		// A bare "web" only next to other release words, "Charlotte's Web" is a title
+		word(`web[ .-]?dl|\d{3,4}p[ .-]web|web[ .-][hx][ .]?26[45]`, "WEB-DL"),
+		word(`web[ .-]?rip`, "WEBRip"),
+		word(`hdtv|pdtv`, "HDTV"),
+		word(`dvd[ .-]?rip|dvd[ .-]?r|dvd`, "DVDRip"),
+		word(`hd[ .-]?rip`, "HDRip"),
+		word(`hd[ .-]?cam|cam[ .-]?rip|telesync|hdts`, "CAM"),
+	}
+	videoCodecs = []releasePattern{
+		word(`[xh][ .]?265|hevc`, "H.265"),
+		word(`[xh][ .]?264|avc`, "H.264"),
+		word(`av1`, "AV1"),
+		word(`vp9`, "VP9"),
+		word(`xvid`, "XviD"),
+		word(`divx`, "DivX"),
+		word(`mpeg[ .-]?2`, "MPEG-2"),
+	}
+	audioCodecs = []releasePattern{
+		audio(`truehd`, "TrueHD"),
+		audio(`dts[ .-]?hd[ .-]?ma|dts[ .-]?ma`, "DTS-HD MA"),
+		audio(`dts[ .-]?x`, "DTS-X"),
+		audio(`dts[ .-]?hd`, "DTS-HD"),
+		audio(`dts`, "DTS"),
+		audio(`e[ .-]?ac[ .-]?3|ddp|dd\+|ddplus`, "EAC3"),
+		audio(`ac[ .-]?3|dd`, "AC3"),
+		audio(`aac`, "AAC"),
+		audio(`flac`, "FLAC"),
+		audio(`l?pcm`, "PCM"),
+		audio(`opus`, "Opus"),
+		audio(`mp3`, "MP3"),
+	}
+	editions = []releasePattern{
+		word(`director'?s[ .-]?cut`, "Director's Cut"),
+		word(`extended(?:[ .-]?(?:cut|edition))?`, "Extended"),
+		word(`theatrical(?:[ .-]?cut)?`, "Theatrical"),
+		word(`unrated`, "Unrated"),
+		word(`uncut`, "Uncut"),
+		word(`final[ .-]?cut`, "Final Cut"),
+		word(`ultimate[ .-]?edition`, "Ultimate Edition"),
+		word(`special[ .-]?edition`, "Special Edition"),
+		word(`collector'?s[ .-]?edition`, "Collector's Edition"),
+		word(`criterion`, "Criterion"),
+		word(`remastered`, "Remastered"),
+		word(`imax`, "IMAX"),
+	}
+
+	atmos  = word(`atmos`, "Atmos")
+	repack = word(`repack\d?|rerip`, "")
+	proper = word(`proper`, "")
+
+	// Channels right after an audio codec, e.g. DDP5.1, AAC2.0, TrueHD.7.1
+	codecChannels = regexp.MustCompile(`(?:dd\+?|ddp|aac|ac3|eac3|dts(?:[ .-]?hd)?(?:[ .-]?ma)?|truehd|atmos|flac|opus|pcm)[ ._-]?([1-9])[ ._]([01])(?:ch)?(?:$|[^0-9])`)
+	// Surround layouts alone are unambiguous enough
+	looseChannels = regexp.MustCompile(`(?:^|[^0-9.])([57])[ ._]1(?:ch)?(?:$|[^0-9])`)
+
+	// "Name-GROUP" at the end, or "[Group] Name" at the start as fansubs do
+	trailingGroup = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\[[^\]]*\])?$`)
+	leadingGroup  = regexp.MustCompile(`^\[([^\]]+)\]`)
+	// What follows a dash without being a group: a number ("1x01-02"), the end
+	// of an episode range ("S01E01-E03") or a resolution ("S01E01-720p")
+	notGroupWord = regexp.MustCompile(`(?i)^(?:\d+|e\d+|\d{3,4}[pi]|[248]k)$`)
+)
+
+// extractRelease reads the encode attributes from a release name, without extension.
+// It works on the raw name because tokenizing would split things like "WEB-DL" or "DDP5.1".
+func extractRelease(name string) models.Release {
+	lower := strings.ToLower(name)
+
+	var release models.Release
+	release.Resolution = firstMatch(resolutions, lower)
+	release.Source = firstMatch(sources, lower)
+	release.VideoCodec = firstMatch(videoCodecs, lower)
+	release.AudioCodec = firstMatch(audioCodecs, lower)
+	if atmos.re.MatchString(lower) {
+		release.AudioCodec = strings.TrimSpace(release.AudioCodec + " Atmos")
+	}
+	release.Edition = firstMatch(editions, lower)
+	release.Repack = repack.re.MatchString(lower)
+	release.Proper = proper.re.MatchString(lower)
+
+	if m := codecChannels.FindStringSubmatch(lower); m != nil {
+		release.AudioChannels = m[1] + "." + m[2]
+	} else if m := looseChannels.FindStringSubmatch(lower); m != nil {
+		release.AudioChannels = m[1] + ".1"
+	}
+
+	release.HDR = hdrFormats(lower)
+	release.ReleaseGroup = releaseGroup(name, release)
+	return release
+}
+
+// audio is word for audio codecs, which are often glued to their channels, e.g. "DDP5.1"
+func audio(pattern, value string) releasePattern {
+	return word(`(?:`+pattern+`)(?:[ .-]?[1-9][ .][01])?`, value)
+}
+
+func firstMatch(patterns []releasePattern, lower string) string {
+	for _, p := range patterns {
+		if p.re.MatchString(lower) {
+			return p.value
+		}
+	}
+	return ""
+}
+
+var (
+	hdr10Plus   = word(`hdr10(?:\+|plus|p)`, "HDR10+")
+	hdr10       = word(`hdr10`, "HDR10")
+	hdr         = word(`hdr`, "HDR")
+	dolbyVision = word(`dv|dovi|dolby[ .-]?vision`, "DV")
+	hlg         = word(`hlg`, "HLG")
+)
+
+func hdrFormats(lower string) []string {
+	var formats []string
+	switch {
+	case hdr10Plus.re.MatchString(lower) || strings.Contains(lower, "hdr10+"):
+		formats = append(formats, hdr10Plus.value)
+	case hdr10.re.MatchString(lower):
+		formats = append(formats, hdr10.value)
+	case hdr.re.MatchString(lower):
+		formats = append(formats, hdr.value)
+	}
+	for _, p := range []releasePattern{dolbyVision, hlg} {
+		if p.re.MatchString(lower) {
+			formats = append(formats, p.value)
+		}
+	}
+	return formats
+}
+
+// Trailing words that look like a group but are the end of another attribute
+var notGroups = map[string]bool{
+	"dl": true, "rip": true, "hd": true, "ma": true, "x": true, "ray": true,
+	"264": true, "265": true, "x264": true, "x265": true, "hevc": true, "avc": true,
+}
+
+// releaseGroup reads the group from a name whose other attributes are already
+// in release. A trailing "-Word" only counts in a name that reads as a release,
+// with a resolution, source or codec, or "Spider-Man" would be by "Man".
+func releaseGroup(name string, release models.Release) string {
+	if m := leadingGroup.FindStringSubmatch(name); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	if release.Resolution == "" && release.Source == "" && release.VideoCodec == "" && release.AudioCodec == "" {
+		return ""
+	}
+	m := trailingGroup.FindStringSubmatch(name)
+	if m == nil || notGroups[strings.ToLower(m[1])] || notGroupWord.MatchString(m[1]) {
+		return ""
+	}
+	return m[1]
+}
@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// "Season 2", "Season.02", "S02", also the season of a folder named "S02E05"
+	folderSeason = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:season[ ._-]?|s)(\d{1,3})(?:$|[^a-z0-9]|e\d)`)
+	// Folders holding season 0
+	specialsFolder = regexp.MustCompile(`(?i)^(?:specials?|season[ ._-]?0+)$`)
+)
+
+// seasonFromFolder reads the season from the name of a folder, e.g. a season
+// pack like "Show.S02.1080p" or a "Season 02" or "Specials" folder. It also
+// returns where the season starts in name, what comes before is the title.
+func seasonFromFolder(name string) (season int, start int, ok bool) {
+	if specialsFolder.MatchString(strings.TrimSpace(name)) {
+		return 0, 0, true
+	}
+	loc := folderSeason.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return 0, 0, false
+	}
+	season, err := strconv.Atoi(name[loc[2]:loc[3]])
+	if err != nil {
+		return 0, 0, false
+	}
+	return season, loc[0], true
+}
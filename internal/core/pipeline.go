@@ -0,0 +1,262 @@
+package core
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alejandro-bustamante/flick/internal/models"
+	"github.com/alejandro-bustamante/flick/internal/quarantine"
+	"github.com/alejandro-bustamante/flick/internal/watcher"
+)
+
+// job is a file travelling through the pipeline. Each stage fills in its part.
+type job struct {
+	path        string
+	profile     *Profile // Decides the library, template and transfer mode
+	folder      *folderGroup
+	parsed      *models.MediaInfo
+	mediaInfo   *models.MediaInfo
+	destination string
+}
+
+// folderGroup is a download folder the watcher handed over as a whole, shared
+// by the jobs of its files. Its own artwork and info, e.g. the poster of a
+// season pack, go with the first of its files that is placed.
+type folderGroup struct {
+	dir     string
+	claimed atomic.Bool
+}
+
+// claim returns the folder the first time it is called, nil afterwards or for
+// a loose file
+func (g *folderGroup) claim() string {
+	if g == nil || !g.claimed.CompareAndSwap(false, true) {
+		return ""
+	}
+	return g.dir
+}
+
+// pipeline processes stable files in four stages: parse -> lookup -> plan -> transfer.
+// Stages are connected by bounded queues, so a slow stage (usually lookup, which
+// talks to TMDb) makes the previous ones wait instead of piling up work.
+// Transfers are sharded by destination: the same destination always goes to the
+// same transfer worker, so files for one path are placed one at a time and in order.
+type pipeline struct {
+	o       *Organizer
+	workers int
+	// Paths currently in the pipeline, so a rescan can't queue the same file twice
+	mu     sync.Mutex
+	active map[string]struct{}
+}
+
+func newPipeline(o *Organizer, workers int) *pipeline {
+	if workers < 1 {
+		workers = 1
+	}
+	return &pipeline{o: o, workers: workers, active: make(map[string]struct{})}
+}
+
+// run blocks until groups is closed and every job already accepted has finished.
+// Once ctx is cancelled the remaining jobs are dropped without touching their files.
+func (p *pipeline) run(ctx context.Context, groups <-chan watcher.Group) {
+	parsed := make(chan *job, p.workers)
+	found := make(chan *job, p.workers)
+	shards := make([]chan *job, p.workers)
+	for i := range shards {
+		shards[i] = make(chan *job, p.workers)
+	}
+
+	stage(p.workers, func() {
+		for group := range groups {
+			var folder *folderGroup
+			if group.Dir != "" {
+				log.Printf("Organizer received complete folder: %s (%d files)", group.Dir, len(group.Files))
+				folder = &folderGroup{dir: group.Dir}
+			}
+			for _, filePath := range group.Files {
+				log.Printf("Organizer received stable file: %s", filePath)
+				if j := p.parse(filePath, folder); j != nil {
+					parsed <- j
+				}
+			}
+		}
+	}, func() { close(parsed) })
+
+	stage(p.workers, func() {
+		for j := range parsed {
+			if p.lookup(ctx, j) {
+				found <- j
+			} else {
+				p.finish(j)
+			}
+		}
+	}, func() { close(found) })
+
+	stage(p.workers, func() {
+		for j := range found {
+			if p.plan(j) {
+				shards[shardFor(j.destination, len(shards))] <- j
+			} else {
+				p.finish(j)
+			}
+		}
+	}, func() {
+		for _, shard := range shards {
+			close(shard)
+		}
+	})
+
+	var transfers sync.WaitGroup
+	for _, shard := range shards {
+		transfers.Add(1)
+		go func() {
+			defer transfers.Done()
+			for j := range shard {
+				if ctx.Err() != nil {
+					log.Printf("Shutting down, leaving %s for the next run", j.path)
+				} else {
+					// Failures and conflict skips are already logged and journaled
+					p.o.placeAt(j.path, j.mediaInfo, j.destination, j.profile.TransferMode, j.folder)
+				}
+				p.finish(j)
+			}
+		}()
+	}
+	transfers.Wait()
+}
+
+// stage starts n workers and calls done once all of them have returned.
+func stage(n int, work func(), done func()) {
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		done()
+	}()
+}
+
+func (p *pipeline) parse(filePath string, folder *folderGroup) *job {
+	if !p.start(filePath) {
+		log.Printf("File is already being processed, ignoring: %s", filePath)
+		return nil
+	}
+	j := &job{path: filePath, profile: p.o.profileFor(filePath), folder: folder}
+
+	if !p.o.accepts(filePath) {
+		p.finish(j)
+		return nil
+	}
+	parsed, err := p.o.parseFile(filePath, filePath)
+	if err != nil {
+		log.Printf("Could not parse %s: %v", filePath, err)
+		p.o.quarantineFile(filePath, reasonFor(err), err, j.profile)
+		p.finish(j)
+		return nil
+	}
+	j.parsed = parsed
+	return j
+}
+
+// start marks a path as in the pipeline, it returns false if it already was
+func (p *pipeline) start(filePath string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.active[filePath]; ok {
+		return false
+	}
+	p.active[filePath] = struct{}{}
+	return true
+}
+
+// finish must be called exactly once for every job, whatever stage it ends in
+func (p *pipeline) finish(j *job) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.active, j.path)
+}
+
+func (p *pipeline) lookup(ctx context.Context, j *job) bool {
+	if ctx.Err() != nil {
+		log.Printf("Shutting down, leaving %s for the next run", j.path)
+		return false
+	}
+	mediaInfo, err := p.o.lookup(ctx, j.parsed)
+	if ctx.Err() != nil {
+		// Not the file's fault, don't quarantine it
+		log.Printf("Lookup of %s cancelled, leaving it for the next run", j.path)
+		return false
+	}
+	if err != nil {
+		log.Printf("Could not find %s: %v", j.path, err)
+		p.o.quarantineFile(j.path, reasonFor(err), err, j.profile)
+		return false
+	}
+	if mediaInfo.Accuracy < p.o.Config.MinAccuracy {
+		p.o.holdForReview(j.path, mediaInfo, j.profile)
+		return false
+	}
+	j.mediaInfo = mediaInfo
+	return true
+}
+
+func (p *pipeline) plan(j *job) bool {
+	j.destination = p.o.destinationFor(j.path, j.mediaInfo, j.profile)
+	if j.destination == "" {
+		log.Printf("Could not determine final path for: %s", j.path)
+		p.o.quarantineFile(j.path, quarantine.ReasonParseError, ErrParse, j.profile)
+		return false
+	}
+	return true
+}
+
+func shardFor(destination string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(destination))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// keyedMutex hands out one lock per key, created on demand and dropped when unused.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock blocks until key is free and returns the function that releases it.
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
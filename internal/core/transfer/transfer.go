@@ -0,0 +1,176 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+type Mode string
+
+const (
+	Move     Mode = "move"
+	Copy     Mode = "copy"
+	Hardlink Mode = "hardlink"
+	Symlink  Mode = "symlink"
+	Reflink  Mode = "reflink"
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case Move, Copy, Hardlink, Symlink, Reflink:
+		return mode, nil
+	case "":
+		return Move, nil
+	default:
+		return "", fmt.Errorf("unknown transfer mode %q (expected move, copy, hardlink, symlink or reflink)", s)
+	}
+}
+
+// KeepsSource reports whether the source file is still in place after a transfer,
+// e.g. so a torrent client can keep seeding it.
+func (m Mode) KeepsSource() bool {
+	return m != Move
+}
+
+// Transfer places src at dst using the given mode and returns the mode that was
+// actually used. Modes that cannot work across filesystems fall back to a copy.
+// dst must not exist and its parent directory must already be created.
+func Transfer(src, dst string, mode Mode) (Mode, error) {
+	switch mode {
+	case Move:
+		err := os.Rename(src, dst)
+		if errors.Is(err, syscall.EXDEV) {
+			log.Printf("%s and %s are on different filesystems, copying instead of renaming", src, dst)
+			if err := copyVerified(src, dst); err != nil {
+				return mode, err
+			}
+			if err := os.Remove(src); err != nil {
+				return mode, fmt.Errorf("copied to %s but could not remove source: %v", dst, err)
+			}
+			return mode, nil
+		}
+		return mode, err
+
+	case Copy:
+		return mode, copyVerified(src, dst)
+
+	case Hardlink:
+		err := os.Link(src, dst)
+		if errors.Is(err, syscall.EXDEV) {
+			log.Printf("Cannot hardlink %s across filesystems, copying instead", src)
+			return Copy, copyVerified(src, dst)
+		}
+		return mode, err
+
+	case Symlink:
+		abs, err := filepath.Abs(src)
+		if err != nil {
+			return mode, err
+		}
+		return mode, os.Symlink(abs, dst)
+
+	case Reflink:
+		err := reflink(src, dst)
+		if errors.Is(err, errors.ErrUnsupported) || errors.Is(err, syscall.EXDEV) ||
+			errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.EINVAL) {
+			log.Printf("Reflink not supported for %s, copying instead", src)
+			return Copy, copyVerified(src, dst)
+		}
+		return mode, err
+
+	default:
+		return mode, fmt.Errorf("unknown transfer mode %q", mode)
+	}
+}
+
+// Revert undoes a transfer made with the given mode, leaving src as it was before.
+func Revert(src, dst string, mode Mode) error {
+	if _, err := os.Lstat(src); err == nil {
+		if !mode.KeepsSource() {
+			return fmt.Errorf("%s already exists", src)
+		}
+		// The original is still there, the library entry is just an extra reference
+		return os.Remove(dst)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(src), 0777); err != nil {
+		return err
+	}
+	if mode == Symlink {
+		return fmt.Errorf("%s is gone, the symlink at %s cannot restore it", src, dst)
+	}
+	_, err := Transfer(dst, src, Move)
+	return err
+}
+
+// copyVerified copies src into a temporary file next to dst, checks that the
+// written data matches the source and only then renames it into place.
+func copyVerified(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".flick-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	srcHash := sha256.New()
+	if _, err := io.Copy(tmp, io.TeeReader(in, srcHash)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error copying %s: %v", src, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := verify(tmpPath, info.Size(), srcHash.Sum(nil)); err != nil {
+		return fmt.Errorf("verification of copy of %s failed: %v", src, err)
+	}
+
+	os.Chmod(tmpPath, info.Mode().Perm())
+	os.Chtimes(tmpPath, info.ModTime(), info.ModTime())
+
+	return os.Rename(tmpPath, dst)
+}
+
+func verify(path string, size int64, sum []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d", size, n)
+	}
+	if !bytes.Equal(h.Sum(nil), sum) {
+		return fmt.Errorf("checksum mismatch")
+	}
+	return nil
+}
@@ -1,12 +1,13 @@
 package daemon
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"log"
 	"net"
 	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 )
 
 // SocketPath es la ruta donde se creará el archivo de socket Unix.
@@ -16,12 +17,19 @@ const SocketPath = "/tmp/flick.sock"
 // Contiene la lógica para iniciar, detener y manejar conexiones.
 type Daemon struct {
 	listener net.Listener
+	handler  Handler
 	wg       sync.WaitGroup
 	quit     chan struct{}
+	ctx      context.Context // Se cancela al detener el daemon, lo reciben los comandos
+}
+
+// Handler ejecuta los comandos que llegan desde el TUI.
+type Handler interface {
+	Handle(ctx context.Context, req Request) Response
 }
 
 // NewDaemon crea e inicializa una nueva instancia del daemon.
-func NewDaemon() (*Daemon, error) {
+func NewDaemon(handler Handler) (*Daemon, error) {
 	// Asegurarse de que el socket no exista antes de empezar.
 	// Esto previene errores si el daemon anterior no se cerró correctamente.
 	if err := os.RemoveAll(SocketPath); err != nil {
@@ -36,18 +44,15 @@ func NewDaemon() (*Daemon, error) {
 	log.Println("Daemon escuchando en", SocketPath)
 	return &Daemon{
 		listener: listener,
+		handler:  handler,
 		quit:     make(chan struct{}),
 	}, nil
 }
 
 // Start inicia el daemon, comenzando a aceptar conexiones
-// y esperando una señal de interrupción para detenerse.
-func (d *Daemon) Start() {
-	// Creamos un canal para escuchar las señales del sistema operativo.
-	// Esto nos permite atrapar Ctrl+C (SIGINT) o una señal de terminación (SIGTERM)
-	// para apagar el daemon de forma segura.
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// y bloquea hasta que se cancela ctx (por ejemplo con Ctrl+C o SIGTERM).
+func (d *Daemon) Start(ctx context.Context) {
+	d.ctx = ctx
 
 	d.wg.Add(1)
 	go d.acceptConnections()
@@ -55,7 +60,7 @@ func (d *Daemon) Start() {
 	log.Println("Daemon iniciado. Presiona Ctrl+C para detener.")
 
 	// Esperamos a recibir una señal de apagado.
-	<-sigChan
+	<-ctx.Done()
 
 	// Una vez recibida la señal, iniciamos el proceso de apagado.
 	log.Println("Recibida señal de apagado, deteniendo el daemon...")
@@ -102,28 +107,39 @@ func (d *Daemon) acceptConnections() {
 }
 
 // handleConnection maneja la lógica para una conexión de cliente individual.
+// Cada línea recibida es un Request en JSON y se responde con una línea con el Response.
 func (d *Daemon) handleConnection(conn net.Conn) {
 	defer d.wg.Done()
 	defer conn.Close()
 
 	log.Println("Cliente conectado:", conn.RemoteAddr().String())
 
-	// Aquí puedes agregar la lógica para comunicarte con el TUI.
-	// Por ahora, solo enviamos un mensaje de bienvenida.
-	_, err := conn.Write([]byte("¡Bienvenido al daemon de Flick!\n"))
-	if err != nil {
-		log.Println("Error al escribir al cliente:", err)
-		return
-	}
+	// Un cliente inactivo no debe impedir que el daemon se detenga.
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-d.quit:
+			conn.Close()
+		case <-finished:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		var res Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			res = Errorf("petición inválida: %v", err)
+		} else {
+			res = d.handler.Handle(d.ctx, req)
+		}
 
-	// Puedes crear un bucle para leer comandos del TUI aquí.
-	// buffer := make([]byte, 1024)
-	// for {
-	//     n, err := conn.Read(buffer)
-	//     if err != nil {
-	//         log.Println("Cliente desconectado.")
-	//         return
-	//     }
-	//     log.Printf("Recibido: %s", buffer[:n])
-	// }
+		if err := encoder.Encode(res); err != nil {
+			log.Println("Error al escribir al cliente:", err)
+			return
+		}
+	}
+	log.Println("Cliente desconectado.")
 }
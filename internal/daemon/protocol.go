@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Comandos aceptados por el daemon.
+const (
+	CmdReviewList    = "review.list"
+	CmdReviewApprove = "review.approve"
+	CmdReviewRematch = "review.rematch"
+	CmdReviewReject  = "review.reject"
+
+	CmdQuarantineList  = "quarantine.list"
+	CmdQuarantineRetry = "quarantine.retry" // Path vacío reintenta todos
+
+	CmdJournalList      = "journal.list"
+	CmdJournalUndo      = "journal.undo"       // ID de la operación
+	CmdJournalUndoBatch = "journal.undo_batch" // ID del lote
+	CmdJournalUndoSince = "journal.undo_since" // Deshace todo desde Since
+)
+
+// Request es un comando enviado por el cliente (TUI).
+type Request struct {
+	Command string    `json:"command"`
+	ID      string    `json:"id,omitempty"`
+	TMDBID  int       `json:"tmdb_id,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+}
+
+// Response es la respuesta del daemon. Data depende del comando.
+type Response struct {
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// OK crea una respuesta exitosa con data serializada a JSON.
+func OK(data any) Response {
+	if data == nil {
+		return Response{OK: true}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Errorf("error serializando respuesta: %v", err)
+	}
+	return Response{OK: true, Data: raw}
+}
+
+// Errorf crea una respuesta de error.
+func Errorf(format string, args ...any) Response {
+	return Response{Error: fmt.Sprintf(format, args...)}
+}
+
+// Send abre una conexión con el daemon, envía un comando y decodifica
+// el campo Data de la respuesta en out (puede ser nil).
+func Send(req Request, out any) error {
+	conn, err := net.Dial("unix", SocketPath)
+	if err != nil {
+		return fmt.Errorf("no se pudo conectar al daemon: %v", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("el daemon cerró la conexión sin responder")
+	}
+
+	var res Response
+	if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+		return err
+	}
+	if !res.OK {
+		return fmt.Errorf("%s", res.Error)
+	}
+	if out != nil && len(res.Data) > 0 {
+		return json.Unmarshal(res.Data, out)
+	}
+	return nil
+}
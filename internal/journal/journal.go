@@ -0,0 +1,357 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alejandro-bustamante/flick/internal/core/transfer"
+	"github.com/alejandro-bustamante/flick/internal/models"
+)
+
+// Operation is a single file movement performed by the organizer.
+type Operation struct {
+	ID          string           `json:"id"`
+	BatchID     string           `json:"batch_id"`
+	Source      string           `json:"source"`
+	Destination string           `json:"destination"`
+	Mode        transfer.Mode    `json:"mode"`
+	Timestamp   time.Time        `json:"timestamp"`
+	MediaInfo   models.MediaInfo `json:"media_info"`
+	TMDBID      int              `json:"tmdb_id"`
+	// Subtitles, NFOs and artwork placed together with the file, undone with it
+	Companions []Companion `json:"companions,omitempty"`
+	// ID of the operation whose file this one overwrote at Destination
+	Replaces string     `json:"replaces,omitempty"`
+	UndoneAt *time.Time `json:"undone_at,omitempty"`
+	// Set when a later operation overwrote this one's file, it can't be undone then
+	ReplacedBy string `json:"replaced_by,omitempty"`
+}
+
+// Companion is a file that travelled with the main file of an operation.
+type Companion struct {
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Mode        transfer.Mode `json:"mode"`
+}
+
+// Skipped is a file flick decided to leave where it is, e.g. because the library
+// already has a better copy, it could not be identified and there is no
+// unmatched folder, or the user undid its operation. It is not picked up again
+// unless it changes.
+type Skipped struct {
+	Source    string    `json:"source"`
+	Reason    string    `json:"reason"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// entry is one line of the journal file. Exactly one of its kinds is set: a new
+// operation, the ID of an operation that has been rolled back, or a skipped file.
+type entry struct {
+	Op     *Operation `json:"op,omitempty"`
+	Undo   string     `json:"undo,omitempty"`
+	UndoAt time.Time  `json:"undo_at,omitzero"`
+	Skip   *Skipped   `json:"skip,omitempty"`
+}
+
+// Journal is an append-only log of every operation, stored as JSON lines.
+// Rollbacks are appended as well, so the file is never rewritten.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+	ops  []*Operation
+	byID map[string]*Operation
+	// The last skip recorded for each source
+	skipped map[string]Skipped
+}
+
+func Open(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating journal directory: %v", err)
+	}
+
+	j := &Journal{
+		path:    path,
+		byID:    make(map[string]*Operation),
+		skipped: make(map[string]Skipped),
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal %s: %v", path, err)
+	}
+	j.file = file
+
+	return j, nil
+}
+
+func (j *Journal) load() error {
+	file, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading journal %s: %v", j.path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("corrupt journal entry at %s:%d: %v", j.path, line, err)
+		}
+		j.apply(e)
+	}
+	return scanner.Err()
+}
+
+func (j *Journal) apply(e entry) {
+	if e.Op != nil {
+		j.ops = append(j.ops, e.Op)
+		j.byID[e.Op.ID] = e.Op
+		if old, ok := j.byID[e.Op.Replaces]; ok {
+			// Its file is gone, there is nothing left to undo
+			old.ReplacedBy = e.Op.ID
+		}
+		return
+	}
+	if e.Skip != nil {
+		j.skipped[e.Skip.Source] = *e.Skip
+		return
+	}
+	if op, ok := j.byID[e.Undo]; ok {
+		at := e.UndoAt
+		op.UndoneAt = &at
+	}
+}
+
+// append writes the entry to disk before it is applied in memory, so what the
+// journal reports is always what a restart would read back.
+func (j *Journal) append(e entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing journal: %v", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("error syncing journal: %v", err)
+	}
+	j.apply(e)
+	return nil
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// NewBatch returns an ID used to group the operations of a single run,
+// so they can be rolled back together.
+func (j *Journal) NewBatch() string {
+	return newID()
+}
+
+// Record stores a completed operation. ID and Timestamp are filled in when empty.
+func (j *Journal) Record(op Operation) (Operation, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if op.ID == "" {
+		op.ID = newID()
+	}
+	if op.Timestamp.IsZero() {
+		op.Timestamp = time.Now()
+	}
+	if op.TMDBID == 0 {
+		op.TMDBID = op.MediaInfo.TMDBID
+	}
+
+	if err := j.append(entry{Op: &op}); err != nil {
+		return Operation{}, err
+	}
+	return op, nil
+}
+
+// Operations returns a copy of every recorded operation, oldest first.
+func (j *Journal) Operations() []Operation {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	ops := make([]Operation, 0, len(j.ops))
+	for _, op := range j.ops {
+		ops = append(ops, *op)
+	}
+	return ops
+}
+
+// Skip records that the file at source was left where it is on purpose, so
+// later scans don't process it again while it stays the same.
+func (j *Journal) Skip(source, reason string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.skip(source, reason)
+}
+
+func (j *Journal) skip(source, reason string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	return j.append(entry{Skip: &Skipped{
+		Source:    source,
+		Reason:    reason,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		Timestamp: time.Now(),
+	}})
+}
+
+// Handled reports whether a file was already organized from source and that
+// operation has not been undone, or it was skipped and hasn't changed since.
+// Useful when the source is kept in place, e.g. with the copy or hardlink
+// transfer modes.
+func (j *Journal) Handled(source string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, op := range j.ops {
+		if op.Source == source && op.UndoneAt == nil {
+			return true
+		}
+	}
+	if skip, ok := j.skipped[source]; ok {
+		// A new download under the same name deserves another look
+		info, err := os.Stat(source)
+		return err == nil && info.Size() == skip.Size && info.ModTime().Equal(skip.ModTime)
+	}
+	return false
+}
+
+// Placed returns the operation that put the file currently at destination,
+// if flick placed it there.
+func (j *Journal) Placed(destination string) (Operation, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := len(j.ops) - 1; i >= 0; i-- {
+		op := j.ops[i]
+		if op.Destination == destination && op.UndoneAt == nil && op.ReplacedBy == "" {
+			return *op, true
+		}
+	}
+	return Operation{}, false
+}
+
+// Undo rolls back a single operation.
+func (j *Journal) Undo(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	op, ok := j.byID[id]
+	if !ok {
+		return fmt.Errorf("operation %s not found", id)
+	}
+	if op.UndoneAt != nil {
+		return fmt.Errorf("operation %s was already undone", id)
+	}
+	if op.ReplacedBy != "" {
+		return fmt.Errorf("operation %s cannot be undone, its file was replaced by operation %s", id, op.ReplacedBy)
+	}
+	return j.undo(op)
+}
+
+// UndoBatch rolls back every operation of a batch, newest first.
+func (j *Journal) UndoBatch(batchID string) error {
+	return j.undoWhere(func(op *Operation) bool {
+		return op.BatchID == batchID
+	})
+}
+
+// UndoSince rolls back every operation performed at or after t, newest first.
+func (j *Journal) UndoSince(t time.Time) error {
+	return j.undoWhere(func(op *Operation) bool {
+		return !op.Timestamp.Before(t)
+	})
+}
+
+func (j *Journal) undoWhere(match func(op *Operation) bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var errs []error
+	for i := len(j.ops) - 1; i >= 0; i-- {
+		op := j.ops[i]
+		if op.UndoneAt != nil || op.ReplacedBy != "" || !match(op) {
+			continue
+		}
+		if err := j.undo(op); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d operations could not be undone, first error: %v", len(errs), errs[0])
+	}
+	return nil
+}
+
+func (j *Journal) undo(op *Operation) error {
+	mode := op.Mode
+	if mode == "" {
+		// Entries written before transfer modes existed were always renames
+		mode = transfer.Move
+	}
+	for i := len(op.Companions) - 1; i >= 0; i-- {
+		c := op.Companions[i]
+		if _, err := os.Lstat(c.Destination); os.IsNotExist(err) {
+			continue // Already reverted by an undo that failed halfway, or deleted by hand
+		}
+		if err := transfer.Revert(c.Source, c.Destination, c.Mode); err != nil {
+			return fmt.Errorf("cannot undo %s: companion %s: %v", op.ID, c.Destination, err)
+		}
+	}
+	if err := transfer.Revert(op.Source, op.Destination, mode); err != nil {
+		return fmt.Errorf("cannot undo %s: %v", op.ID, err)
+	}
+
+	// Leave no empty title folders behind in the library. Remove fails on
+	// non-empty directories, which is exactly what we want.
+	os.Remove(filepath.Dir(op.Destination))
+
+	if err := j.append(entry{Undo: op.ID, UndoAt: time.Now()}); err != nil {
+		return err
+	}
+	// The file is back in the watch folder, or never left it. Without this
+	// the next event or scan would organize it again with the same match.
+	if err := j.skip(op.Source, "undone"); err != nil {
+		return fmt.Errorf("undid %s but could not record its source as skipped: %v", op.ID, err)
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
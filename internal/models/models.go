@@ -1,20 +1,53 @@
 package models
 
+// UnknownSeason is the season of a file named with an episode alone, e.g.
+// "E05.mkv" inside a season pack, until its folders tell which one it is.
+const UnknownSeason = -1
+
 type MediaInfo struct {
 	Title        string
 	IsSeries     bool
 	Year         int
-	Season       int
-	Episode      int
-	Remaining    []string
-	OriginalName string // For debugging
-	Accuracy     int    // (0-5)
+	Season       int   // 0 holds the specials
+	Episode      int   // The first one if the file has several
+	Episodes     []int // Every episode in the file, e.g. [1 2] for S01E01E02
+	EpisodeTitle string
+	AirDate      string // YYYY-MM-DD, for shows named by date instead of episode
+	// Episode counted from the start of the show, for anime named like "Title - 12".
+	// Season and Episode stay 0 until the finder maps it
+	AbsoluteEpisode int
+	CRC32           string // Checksum of the file from its name, e.g. [ABCD1234]
+	Part            string // One file of a movie split in several: cd1, disc2, part1, pt2
+	Remaining       []string
+	OriginalName    string // For debugging
+	Accuracy        int    // (0-5)
+	TMDBID          int    `naming:"tmdb_id"`
+	Release
+}
+
+// Release describes the encode of a file, as told by its release name,
+// e.g. "Movie.2019.2160p.UHD.BluRay.Remux.HDR.HEVC.TrueHD.Atmos.7.1-GROUP".
+// Empty fields were not found in the name.
+type Release struct {
+	Resolution    string   // 2160p, 1080p, 720p, 576p or 480p
+	Source        string   // Remux, BluRay, WEB-DL, WEBRip, HDTV, DVDRip, HDRip, CAM
+	VideoCodec    string   // H.264, H.265, AV1, XviD...
+	AudioCodec    string   // TrueHD Atmos, DTS-HD MA, EAC3, AC3, AAC...
+	AudioChannels string   // 2.0, 5.1, 7.1
+	HDR           []string // HDR10+, HDR10, HDR, DV, HLG
+	ReleaseGroup  string
+	Repack        bool
+	Proper        bool
+	Edition       string // Director's Cut, Extended, Unrated...
 }
 
 type ParseResult struct {
 	MediaInfo *MediaInfo
 	Errors    []error
 	Warnings  []string
+	// Which part of the path each MediaInfo field was read from, the file name or
+	// a folder, by field name, e.g. "Year" -> "Movie.Name.2019.1080p.BluRay"
+	Sources map[string]string
 }
 
 type TestCase struct {
@@ -25,13 +58,53 @@ type TestCase struct {
 
 type UserSettings struct {
 	Directories struct {
-		Watch  string `toml:"watch"`
-		Movies string `toml:"movies"`
-		Series string `toml:"series"`
+		Watch     string `toml:"watch"`
+		Movies    string `toml:"movies"`
+		Series    string `toml:"series"`
+		Data      string `toml:"data"`      // Journal and other state. Defaults to ~/.local/share/flick
+		Review    string `toml:"review"`    // Optional holding folder for low accuracy matches
+		Unmatched string `toml:"unmatched"` // Optional quarantine for files that could not be organized
 	} `toml:"directories"`
+	Watcher struct {
+		RescanInterval int    `toml:"rescan_interval"` // Seconds between full rescans, 0 disables them
+		Backend        string `toml:"backend"`         // fsnotify or poll, poll for NFS/SMB/FUSE watch folders
+		PollInterval   int    `toml:"poll_interval"`   // Seconds between listings with the poll backend
+		MaxPending     int    `toml:"max_pending"`     // Files waiting to become stable before new ones wait for room
+		GroupByFolder  bool   `toml:"group_by_folder"` // Wait for whole download folders, e.g. season packs
+		CheckWriters   bool   `toml:"check_writers"`   // Also wait while a process has the file open for writing (Linux)
+	} `toml:"watcher"`
+	Organizer struct {
+		TransferMode string `toml:"transfer_mode"` // move, copy, hardlink, symlink or reflink
+		OnConflict   string `toml:"on_conflict"`   // skip, overwrite, keep_both or upgrade
+		MinAccuracy  int    `toml:"min_accuracy"`  // 0-5, lower matches wait for review
+		Workers      int    `toml:"workers"`       // Files processed concurrently per stage
+		// Check the CRC32 that anime releases put in their names, reading the whole file
+		VerifyChecksums bool `toml:"verify_checksums"`
+		// Seconds to wait for in-flight files on shutdown before leaving them for the next run
+		ShutdownTimeout int `toml:"shutdown_timeout"`
+	} `toml:"organizer"`
+	Naming struct {
+		Movie  string `toml:"movie"`
+		Series string `toml:"series"`
+	} `toml:"naming"`
 	Secrets struct {
 		TMDB_API_Key string `toml:"tmdb_api_key"`
 	} `toml:"secrets"`
+	// One per watch folder. Without any, [directories] is used as the only profile
+	Profiles []Profile `toml:"profiles"`
+}
+
+// Profile is a watch folder feeding its own library. Empty fields fall back to
+// the global [directories], [naming], [organizer] and [watcher] values.
+type Profile struct {
+	Name           string `toml:"name"` // Defaults to the watch folder name
+	Watch          string `toml:"watch"`
+	Movies         string `toml:"movies"`
+	Series         string `toml:"series"`
+	MovieTemplate  string `toml:"movie_template"`
+	SeriesTemplate string `toml:"series_template"`
+	TransferMode   string `toml:"transfer_mode"`
+	Backend        string `toml:"backend"`
 }
 
 type Config struct {
@@ -46,4 +119,12 @@ type Config struct {
 	Extractor struct {
 		YearRange [2]int `toml:"year_range"`
 	} `toml:"extractor"`
+	Filter struct {
+		Extensions  []string `toml:"extensions"`   // Video extensions to organize, a default list if empty
+		Include     []string `toml:"include"`      // Globs, if set only matching files are organized
+		Exclude     []string `toml:"exclude"`      // Globs for files to ignore
+		MinSize     int64    `toml:"min_size"`     // Bytes
+		AllowHidden bool     `toml:"allow_hidden"` // Dot files and folders are ignored unless set
+		IgnoreWords []string `toml:"ignore_words"` // Defaults to sample and trailer
+	} `toml:"filter"`
 }
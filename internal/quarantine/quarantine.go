@@ -0,0 +1,203 @@
+package quarantine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alejandro-bustamante/flick/internal/core/transfer"
+)
+
+// Reasons a file could not be organized.
+const (
+	ReasonParseError   = "parse_error"
+	ReasonNoResults    = "no_results"
+	ReasonNetworkError = "network_error"
+	ReasonLowAccuracy  = "low_accuracy"
+	ReasonBadChecksum  = "bad_checksum"
+	ReasonIOError      = "io_error"
+)
+
+// sidecarExt is appended to the file name to store why the file is here,
+// e.g. "movie.mkv" -> "movie.mkv.flick.json".
+const sidecarExt = ".flick.json"
+
+// Record is the content of a sidecar file.
+type Record struct {
+	Path         string    `json:"path"`          // Where the file is now, inside the quarantine folder unless InPlace
+	OriginalPath string    `json:"original_path"` // Where it was first found
+	Reason       string    `json:"reason"`
+	Error        string    `json:"error,omitempty"`
+	Time         time.Time `json:"time"`
+	Attempts     int       `json:"attempts"`
+	// The file was left where it was found, as the transfer mode keeps sources
+	// (e.g. a seeding torrent). Only the record is in the quarantine folder.
+	InPlace bool `json:"in_place,omitempty"`
+}
+
+// Quarantine is a folder for files that could not be organized. Each file has
+// a sidecar record next to it, so the folder is self describing.
+type Quarantine struct {
+	Dir string
+}
+
+func New(dir string) *Quarantine {
+	return &Quarantine{Dir: dir}
+}
+
+// Add moves the file into the quarantine folder, or just updates its record if
+// it is already there (e.g. a retry that failed again). With a mode that keeps
+// its sources the file is not touched, only its record is written.
+func (q *Quarantine) Add(filePath, reason string, cause error, mode transfer.Mode) (Record, error) {
+	if err := os.MkdirAll(q.Dir, 0777); err != nil {
+		return Record{}, err
+	}
+
+	record := Record{
+		Path:         filePath,
+		OriginalPath: filePath,
+		Reason:       reason,
+		Time:         time.Now(),
+		Attempts:     1,
+	}
+	if cause != nil {
+		record.Error = cause.Error()
+	}
+
+	var sidecar string
+	if q.Contains(filePath) {
+		sidecar = filePath + sidecarExt
+		if previous, err := readRecord(sidecar); err == nil {
+			record.OriginalPath = previous.OriginalPath
+			record.Attempts = previous.Attempts + 1
+		}
+	} else if previous, found, ok := q.inPlace(filePath); ok {
+		record.OriginalPath = previous.OriginalPath
+		record.Attempts = previous.Attempts + 1
+		record.InPlace = true
+		sidecar = found
+	} else if mode.KeepsSource() {
+		record.InPlace = true
+		sidecar = q.freePath(filePath) + sidecarExt
+	} else {
+		dest := q.freePath(filePath)
+		if _, err := transfer.Transfer(filePath, dest, transfer.Move); err != nil {
+			return Record{}, fmt.Errorf("error moving %s to quarantine: %v", filePath, err)
+		}
+		record.Path = dest
+		sidecar = dest + sidecarExt
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return Record{}, err
+	}
+	if err := os.WriteFile(sidecar, data, 0644); err != nil {
+		return Record{}, fmt.Errorf("error writing quarantine record: %v", err)
+	}
+	return record, nil
+}
+
+// freePath picks a name in the quarantine folder for the file, neither taken by
+// a file nor by a record.
+func (q *Quarantine) freePath(filePath string) string {
+	ext := filepath.Ext(filePath)
+	dest := filepath.Join(q.Dir, filepath.Base(filePath))
+	for i := 2; ; i++ {
+		_, errFile := os.Lstat(dest)
+		_, errRecord := os.Lstat(dest + sidecarExt)
+		if os.IsNotExist(errFile) && os.IsNotExist(errRecord) {
+			return dest
+		}
+		dest = filepath.Join(q.Dir, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(filepath.Base(filePath), ext), i, ext))
+	}
+}
+
+// inPlace finds the record of a file left outside the quarantine folder, and
+// the path of its sidecar.
+func (q *Quarantine) inPlace(filePath string) (Record, string, bool) {
+	entries, err := os.ReadDir(q.Dir)
+	if err != nil {
+		return Record{}, "", false
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), sidecarExt) {
+			continue
+		}
+		sidecar := filepath.Join(q.Dir, entry.Name())
+		if record, err := readRecord(sidecar); err == nil && record.InPlace && record.Path == filePath {
+			return record, sidecar, true
+		}
+	}
+	return Record{}, "", false
+}
+
+// Contains reports whether path is inside the quarantine folder, either a
+// quarantined file or one of the sidecar records.
+func (q *Quarantine) Contains(path string) bool {
+	if q.Dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(q.Dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// List returns every quarantined file, oldest first.
+func (q *Quarantine) List() ([]Record, error) {
+	entries, err := os.ReadDir(q.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), sidecarExt) {
+			continue
+		}
+		record, err := readRecord(filepath.Join(q.Dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
+
+// Release deletes the record of a file that left the quarantine.
+func (q *Quarantine) Release(path string) error {
+	sidecar := path + sidecarExt
+	if !q.Contains(path) {
+		_, found, ok := q.inPlace(path)
+		if !ok {
+			return nil
+		}
+		sidecar = found
+	}
+	err := os.Remove(sidecar)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func readRecord(sidecarPath string) (Record, error) {
+	data, err := os.ReadFile(sidecarPath)
+	if err != nil {
+		return Record{}, err
+	}
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
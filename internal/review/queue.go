@@ -0,0 +1,151 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alejandro-bustamante/flick/internal/models"
+)
+
+// Item is a file whose best match was not accurate enough to be organized
+// automatically and is waiting for a human decision.
+type Item struct {
+	ID           string           `json:"id"`
+	Path         string           `json:"path"`          // Where the file is now
+	OriginalPath string           `json:"original_path"` // Where it was found
+	Candidate    models.MediaInfo `json:"candidate"`     // Best match found on TMDb
+	Added        time.Time        `json:"added"`
+}
+
+// Queue is the persistent list of items pending review. The whole queue is
+// rewritten on every change, it is expected to stay small.
+type Queue struct {
+	path  string
+	mu    sync.Mutex
+	items map[string]Item
+}
+
+func Open(path string) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating review queue directory: %v", err)
+	}
+
+	q := &Queue{
+		path:  path,
+		items: make(map[string]Item),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading review queue %s: %v", path, err)
+	}
+
+	var items []Item
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("corrupt review queue %s: %v", path, err)
+	}
+	for _, item := range items {
+		q.items[item.ID] = item
+	}
+	return q, nil
+}
+
+func (q *Queue) Add(item Item) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if item.ID == "" {
+		item.ID = fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	if item.Added.IsZero() {
+		item.Added = time.Now()
+	}
+	q.items[item.ID] = item
+
+	if err := q.save(); err != nil {
+		delete(q.items, item.ID)
+		return Item{}, err
+	}
+	return item, nil
+}
+
+func (q *Queue) Get(id string) (Item, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[id]
+	return item, ok
+}
+
+// Contains reports whether the file at path is waiting for review.
+func (q *Queue) Contains(path string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, item := range q.items {
+		if item.Path == path || item.OriginalPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the pending items, oldest first.
+func (q *Queue) List() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := make([]Item, 0, len(q.items))
+	for _, item := range q.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Added.Before(items[j].Added)
+	})
+	return items
+}
+
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[id]
+	if !ok {
+		return fmt.Errorf("review item %s not found", id)
+	}
+	delete(q.items, id)
+
+	if err := q.save(); err != nil {
+		q.items[id] = item
+		return err
+	}
+	return nil
+}
+
+// save writes the queue to a temporary file and renames it over the old one,
+// so a crash never leaves a half written queue behind.
+func (q *Queue) save() error {
+	items := make([]Item, 0, len(q.items))
+	for _, item := range q.items {
+		items = append(items, item)
+	}
+
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing review queue: %v", err)
+	}
+	return os.Rename(tmp, q.path)
+}
@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/alejandro-bustamante/flick/internal/daemon"
+	"github.com/alejandro-bustamante/flick/internal/review"
+)
+
+// reviewItem muestra un archivo pendiente de revisión con su mejor candidato.
+type reviewItem struct{ review.Item }
+
+func (i reviewItem) Title() string { return filepath.Base(i.Path) }
+func (i reviewItem) Description() string {
+	c := i.Candidate
+	return fmt.Sprintf("%s (%d) · precisión %d/5", c.Title, c.Year, c.Accuracy)
+}
+func (i reviewItem) FilterValue() string { return filepath.Base(i.Path) }
+
+type reviewItemsMsg []list.Item
+type reviewStatusMsg string
+
+// reviewErrMsg es un fallo al hablar con el daemon; no recarga la cola.
+type reviewErrMsg struct{ error }
+
+// reviewModel es el panel derecho: la cola de revisión del daemon.
+type reviewModel struct {
+	list     list.Model
+	tmdbID   textinput.Model
+	matching bool // Esperando el TMDb ID para re-match
+	status   string
+}
+
+func newReviewModel() reviewModel {
+	l := list.New(nil, list.NewDefaultDelegate(), rightPaneWidth-4, 20)
+	l.Title = "Pendientes de revisión"
+
+	ti := textinput.New()
+	ti.Placeholder = "TMDb ID"
+	ti.CharLimit = 10
+
+	return reviewModel{
+		list:   l,
+		tmdbID: ti,
+		status: "tab: cambiar panel · a: aprobar · m: re-match · x: rechazar · R: recargar",
+	}
+}
+
+func fetchReview() tea.Cmd {
+	return func() tea.Msg {
+		var items []review.Item
+		if err := daemon.Send(daemon.Request{Command: daemon.CmdReviewList}, &items); err != nil {
+			return reviewErrMsg{err}
+		}
+		listItems := make([]list.Item, 0, len(items))
+		for _, it := range items {
+			listItems = append(listItems, reviewItem{it})
+		}
+		return reviewItemsMsg(listItems)
+	}
+}
+
+// sendReview ejecuta un comando sobre un elemento; si sale bien se recarga la cola.
+func sendReview(req daemon.Request, done string) tea.Cmd {
+	return func() tea.Msg {
+		if err := daemon.Send(req, nil); err != nil {
+			return reviewErrMsg{err}
+		}
+		return reviewStatusMsg(done)
+	}
+}
+
+func (r reviewModel) selected() (reviewItem, bool) {
+	it, ok := r.list.SelectedItem().(reviewItem)
+	return it, ok
+}
+
+func (r reviewModel) Update(msg tea.Msg) (reviewModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case reviewItemsMsg:
+		r.list.SetItems(msg)
+		return r, nil
+
+	case reviewStatusMsg:
+		r.status = string(msg)
+		return r, fetchReview()
+
+	case reviewErrMsg:
+		r.status = "Error: " + msg.Error()
+		return r, nil
+
+	case tea.KeyMsg:
+		if r.matching {
+			switch msg.String() {
+			case "esc":
+				r.matching = false
+				r.tmdbID.Blur()
+				return r, nil
+			case "enter":
+				r.matching = false
+				r.tmdbID.Blur()
+				it, ok := r.selected()
+				id, err := strconv.Atoi(r.tmdbID.Value())
+				if !ok || err != nil {
+					r.status = "TMDb ID inválido"
+					return r, nil
+				}
+				req := daemon.Request{Command: daemon.CmdReviewRematch, ID: it.ID, TMDBID: id}
+				return r, sendReview(req, "Re-match aplicado")
+			}
+			var cmd tea.Cmd
+			r.tmdbID, cmd = r.tmdbID.Update(msg)
+			return r, cmd
+		}
+
+		switch msg.String() {
+		case "R":
+			return r, fetchReview()
+		case "a":
+			if it, ok := r.selected(); ok {
+				req := daemon.Request{Command: daemon.CmdReviewApprove, ID: it.ID}
+				return r, sendReview(req, "Aprobado: "+it.Candidate.Title)
+			}
+			return r, nil
+		case "x":
+			if it, ok := r.selected(); ok {
+				req := daemon.Request{Command: daemon.CmdReviewReject, ID: it.ID}
+				return r, sendReview(req, "Rechazado: "+it.Title())
+			}
+			return r, nil
+		case "m":
+			if _, ok := r.selected(); ok {
+				r.matching = true
+				r.tmdbID.SetValue("")
+				return r, r.tmdbID.Focus()
+			}
+			return r, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	r.list, cmd = r.list.Update(msg)
+	return r, cmd
+}
+
+func (r reviewModel) View() string {
+	footer := r.status
+	if r.matching {
+		footer = "Re-match: " + r.tmdbID.View()
+	}
+	return r.list.View() + "\n" + footer
+}
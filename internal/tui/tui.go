@@ -19,10 +19,12 @@ func (i item) Description() string { return "" }
 func (i item) FilterValue() string { return string(i) }
 
 type model struct {
-	currentDir string
-	items      []list.Item
-	list       list.Model
-	err        error
+	currentDir  string
+	items       []list.Item
+	list        list.Model
+	review      reviewModel
+	focusReview bool
+	err         error
 }
 
 var (
@@ -51,11 +53,12 @@ func initialModel() model {
 	return model{
 		currentDir: dir,
 		list:       l,
+		review:     newReviewModel(),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return m.readDir()
+	return tea.Batch(m.readDir(), fetchReview())
 }
 
 func (m model) readDir() tea.Cmd {
@@ -98,11 +101,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.err = msg
 		return m, nil
 
+	case reviewItemsMsg, reviewStatusMsg, reviewErrMsg:
+		m.review, cmd = m.review.Update(msg)
+		return m, cmd
+
 	case tea.KeyMsg:
+		// Mientras se escribe un TMDb ID todas las teclas van al panel de revisión
+		if m.review.matching {
+			m.review, cmd = m.review.Update(msg)
+			return m, cmd
+		}
+
 		switch msg.String() {
 		case "q":
 			return m, tea.Quit
 
+		case "tab":
+			m.focusReview = !m.focusReview
+			return m, nil
+		}
+
+		if m.focusReview {
+			m.review, cmd = m.review.Update(msg)
+			return m, cmd
+		}
+
+		switch msg.String() {
 		case "enter":
 			selected := m.list.SelectedItem().(item)
 			selectedStr := string(selected)
@@ -125,7 +149,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m model) View() string {
 	left := styles.leftPane.Render(m.list.View())
-	right := styles.rightPane.Render("") // Reservado para futuro
+	right := styles.rightPane.Render(m.review.View())
 	return lipgloss.JoinHorizontal(lipgloss.Top, left, right)
 }
 
@@ -0,0 +1,65 @@
+package watcher
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// BackendKind selects how a watch path learns about changes.
+type BackendKind string
+
+const (
+	// Fsnotify uses the kernel notifications (inotify on Linux). The default.
+	Fsnotify BackendKind = "fsnotify"
+	// Poll lists the watched directories on an interval and diffs the results.
+	// For NFS/SMB/FUSE mounts, where changes made by other machines are never notified.
+	Poll BackendKind = "poll"
+)
+
+// ParseBackendKind validates a backend name from the settings. Empty means fsnotify.
+func ParseBackendKind(s string) (BackendKind, error) {
+	switch kind := BackendKind(s); kind {
+	case "":
+		return Fsnotify, nil
+	case Fsnotify, Poll:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("unknown watcher backend %q (valid: fsnotify, poll)", s)
+	}
+}
+
+// Backend reports changes in a set of directories, not recursive: every
+// subdirectory that must be watched is added on its own. Events use the
+// fsnotify types whatever the backend.
+type Backend interface {
+	Add(path string) error
+	Remove(path string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+// NewBackend creates the backend of the given kind. pollInterval is only used by Poll.
+func NewBackend(kind BackendKind, pollInterval time.Duration) (Backend, error) {
+	switch kind {
+	case "", Fsnotify:
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, err
+		}
+		return fsnotifyBackend{w}, nil
+	case Poll:
+		return newPollBackend(pollInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown watcher backend %q", kind)
+	}
+}
+
+type fsnotifyBackend struct {
+	*fsnotify.Watcher
+}
+
+func (b fsnotifyBackend) Events() <-chan fsnotify.Event { return b.Watcher.Events }
+func (b fsnotifyBackend) Errors() <-chan error          { return b.Watcher.Errors }
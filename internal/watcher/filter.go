@@ -0,0 +1,106 @@
+package watcher
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultVideoExtensions is used when patterns.toml doesn't list any.
+var DefaultVideoExtensions = []string{
+	".mkv", ".mp4", ".avi", ".m4v", ".mov", ".wmv", ".mpg", ".mpeg",
+	".ts", ".m2ts", ".webm", ".flv", ".ogv", ".divx",
+}
+
+// DefaultIgnoreWords is used when patterns.toml doesn't set ignore_words.
+var DefaultIgnoreWords = []string{"sample", "trailer"}
+
+// Filter decides which files in a watch folder are worth organizing.
+// The zero value lets everything through.
+type Filter struct {
+	Extensions   []string // Allowed extensions, any if empty
+	Include      []string // Globs, if any is set a file must match one of them
+	Exclude      []string // Globs, a file matching any of them is ignored
+	MinSize      int64    // Bytes, checked once the file is stable
+	IgnoreHidden bool     // Dot files and anything inside dot folders
+	IgnoreWords  []string // A folder or the last word of the name, e.g. "sample"
+}
+
+// Allows checks everything but the size. rel is the path relative to the
+// watch folder. Globs are matched against the file name and against every run
+// of folders in rel, so "*.nfo" works anywhere and "Extras/*" ignores an
+// Extras folder at any depth, with everything inside it.
+func (f Filter) Allows(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	name := filepath.Base(rel)
+
+	if len(f.Extensions) > 0 && !slices.Contains(f.Extensions, strings.ToLower(filepath.Ext(name))) {
+		return false
+	}
+	if f.IgnoreHidden && hidden(rel) {
+		return false
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, rel, name) {
+		return false
+	}
+	if matchAny(f.Exclude, rel, name) {
+		return false
+	}
+	return !f.ignored(rel)
+}
+
+// ignored reports whether rel is tagged with one of the ignore words: as the
+// last word of the file name ("Movie-sample.mkv", "Movie.2019.Trailer.mkv") or
+// as a whole folder name, maybe plural ("Sample/", "Trailers/"). Anywhere else
+// the word is likely part of a title, e.g. "Trailer.Park.Boys.S01E01.mkv".
+func (f Filter) ignored(rel string) bool {
+	parts := strings.Split(strings.ToLower(rel), "/")
+	folders, name := parts[:len(parts)-1], parts[len(parts)-1]
+	words := strings.FieldsFunc(strings.TrimSuffix(name, filepath.Ext(name)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range f.IgnoreWords {
+		word = strings.ToLower(word)
+		if len(words) > 0 && words[len(words)-1] == word {
+			return true
+		}
+		if slices.Contains(folders, word) || slices.Contains(folders, word+"s") {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsSize reports whether a file of the given size is big enough.
+func (f Filter) AllowsSize(size int64) bool {
+	return size >= f.MinSize
+}
+
+func hidden(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(globs []string, rel, name string) bool {
+	parts := strings.Split(rel, "/")
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+		// Every run of consecutive elements, so a folder matches wherever it
+		// sits and a match on a folder covers what is inside it
+		for i := range parts {
+			for j := i + 1; j <= len(parts); j++ {
+				if ok, _ := filepath.Match(glob, strings.Join(parts[i:j], "/")); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Group is what the watcher hands over once it is ready: a single file or,
+// with GroupByFolder, every eligible file of a top-level download folder.
+type Group struct {
+	Dir   string   // The download folder, empty for a single loose file
+	Files []string // Files to organize, at least one
+}
+
+// groupKey is the pending set key for a path. With GroupByFolder every file
+// inside a top-level folder of the watch folder shares that folder as its key,
+// so the folder is only ready once nothing in it has changed for the delay.
+func (fw *FolderWatcher) groupKey(path string) string {
+	if !fw.Config.GroupByFolder {
+		return path
+	}
+	rel, err := filepath.Rel(fw.Config.Path, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	top, _, nested := strings.Cut(rel, string(filepath.Separator))
+	if !nested {
+		return path // A loose file right in the watch folder
+	}
+	return filepath.Join(fw.Config.Path, top)
+}
+
+// collectGroup lists the files of a ready folder that should be organized
+func (fw *FolderWatcher) collectGroup(dir string) Group {
+	group := Group{Dir: dir}
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && fw.Config.Filter.IgnoreHidden && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fw.shouldProcessFile(path) || (fw.Config.Skip != nil && fw.Config.Skip(path)) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || !fw.Config.Filter.AllowsSize(info.Size()) {
+			return nil
+		}
+		group.Files = append(group.Files, path)
+		return nil
+	})
+	slices.Sort(group.Files)
+	return group
+}
@@ -0,0 +1,174 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultMaxPending = 10000
+
+// pendingSet holds the files waiting to become stable, one entry per path.
+// It never drops a path: once it holds max entries, adding a new one blocks
+// until some file leaves, which in turn slows down the event loop and the
+// scans instead of losing work. If a state file is set, the paths are saved
+// to it so they survive a restart.
+type pendingSet struct {
+	mu      sync.Mutex
+	room    *sync.Cond
+	entries map[string]time.Time
+	max     int
+	path    string // State file, empty to keep everything in memory
+	dirty   bool
+	clock   Clock
+}
+
+func newPendingSet(max int, statePath string, clock Clock) *pendingSet {
+	if max <= 0 {
+		max = defaultMaxPending
+	}
+	p := &pendingSet{
+		entries: make(map[string]time.Time),
+		max:     max,
+		path:    statePath,
+		clock:   clock,
+	}
+	p.room = sync.NewCond(&p.mu)
+	return p
+}
+
+// load restores the paths saved by a previous run. Their delay starts over.
+func (p *pendingSet) load() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return fmt.Errorf("error reading pending files %s: %v", p.path, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	for _, path := range paths {
+		p.entries[path] = now
+	}
+	return nil
+}
+
+// add queues a path. With reset, a path already pending starts its delay over
+// (it just changed); without it, its current delay is kept (a scan found it
+// again). It blocks while the set is full and returns false if ctx ends first.
+func (p *pendingSet) add(ctx context.Context, path string, reset bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.entries[path]; ok {
+		if reset {
+			p.entries[path] = p.clock.Now()
+		}
+		return true
+	}
+
+	if len(p.entries) >= p.max {
+		log.Printf("%d files already pending, waiting for room to add %s", len(p.entries), path)
+		// Cond can't select on ctx, wake the waiters when it is done
+		stop := context.AfterFunc(ctx, func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.room.Broadcast()
+		})
+		defer stop()
+		for len(p.entries) >= p.max && ctx.Err() == nil {
+			p.room.Wait()
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+
+	p.entries[path] = p.clock.Now()
+	p.dirty = true
+	return true
+}
+
+func (p *pendingSet) remove(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.entries[path]; ok {
+		delete(p.entries, path)
+		p.dirty = true
+		p.room.Signal()
+	}
+}
+
+// touch restarts the delay of a path that is still pending
+func (p *pendingSet) touch(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.entries[path]; ok {
+		p.entries[path] = p.clock.Now()
+	}
+}
+
+// due returns the paths that have been pending for at least delay
+func (p *pendingSet) due(delay time.Duration) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	var paths []string
+	for path, added := range p.entries {
+		if now.Sub(added) >= delay {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// save writes the paths to the state file if they changed since the last
+// save. Like the review queue, through a temporary file and a rename.
+func (p *pendingSet) save() error {
+	p.mu.Lock()
+	if p.path == "" || !p.dirty {
+		p.mu.Unlock()
+		return nil
+	}
+	paths := make([]string, 0, len(p.entries))
+	for path := range p.entries {
+		paths = append(paths, path)
+	}
+	p.dirty = false
+	p.mu.Unlock()
+
+	data, err := json.MarshalIndent(paths, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(p.path), 0755)
+	}
+	if err == nil {
+		tmp := p.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, p.path)
+		}
+	}
+	if err != nil {
+		p.mu.Lock()
+		p.dirty = true // Try again on the next save
+		p.mu.Unlock()
+		return fmt.Errorf("error saving pending files: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const defaultPollInterval = 5 * time.Second
+
+// entryState is what the poller remembers of a directory entry between listings
+type entryState struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+// pollBackend emulates fsnotify by listing every watched directory on an
+// interval. A new entry is a Create, a file whose size or modification time
+// changed is a Write and an entry that is gone is a Remove.
+type pollBackend struct {
+	interval time.Duration
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	mu   sync.Mutex
+	dirs map[string]map[string]entryState
+}
+
+func newPollBackend(interval time.Duration) *pollBackend {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	b := &pollBackend{
+		interval: interval,
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+		dirs:     make(map[string]map[string]entryState),
+	}
+	b.wg.Add(1)
+	go b.loop()
+	return b
+}
+
+// Add starts polling path. What is already in it is taken as the baseline and
+// produces no events, the same as with fsnotify.
+func (b *pollBackend) Add(path string) error {
+	path = filepath.Clean(path)
+	snapshot, err := listDir(path)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.dirs[path]; !ok {
+		b.dirs[path] = snapshot
+	}
+	return nil
+}
+
+func (b *pollBackend) Remove(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.dirs, filepath.Clean(path))
+	return nil
+}
+
+func (b *pollBackend) Events() <-chan fsnotify.Event { return b.events }
+func (b *pollBackend) Errors() <-chan error          { return b.errors }
+
+func (b *pollBackend) Close() error {
+	close(b.done)
+	b.wg.Wait()
+	close(b.events)
+	close(b.errors)
+	return nil
+}
+
+func (b *pollBackend) loop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			if !b.poll() {
+				return
+			}
+		}
+	}
+}
+
+// poll lists every directory once and sends the differences. It returns false
+// if the backend was closed meanwhile.
+func (b *pollBackend) poll() bool {
+	b.mu.Lock()
+	paths := make([]string, 0, len(b.dirs))
+	for path := range b.dirs {
+		paths = append(paths, path)
+	}
+	b.mu.Unlock()
+
+	for _, dir := range paths {
+		current, err := listDir(dir)
+		if os.IsNotExist(err) {
+			// The Remove comes from the parent listing, if it is watched
+			b.Remove(dir)
+			continue
+		}
+		if err != nil {
+			if !b.send(nil, err) {
+				return false
+			}
+			continue
+		}
+
+		// Events are sent without the lock, the receiver may call Add or Remove
+		b.mu.Lock()
+		previous, ok := b.dirs[dir]
+		if ok {
+			b.dirs[dir] = current
+		}
+		b.mu.Unlock()
+		if !ok {
+			continue // Removed while listing
+		}
+
+		for _, event := range diff(dir, previous, current) {
+			if !b.send(&event, nil) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (b *pollBackend) send(event *fsnotify.Event, err error) bool {
+	if event != nil {
+		select {
+		case b.events <- *event:
+			return true
+		case <-b.done:
+			return false
+		}
+	}
+	select {
+	case b.errors <- err:
+		return true
+	case <-b.done:
+		return false
+	}
+}
+
+func diff(dir string, previous, current map[string]entryState) []fsnotify.Event {
+	var events []fsnotify.Event
+	for name, now := range current {
+		path := filepath.Join(dir, name)
+		before, ok := previous[name]
+		switch {
+		case !ok || before.isDir != now.isDir:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case !now.isDir && (before.size != now.size || !before.modTime.Equal(now.modTime)):
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			events = append(events, fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+		}
+	}
+	return events
+}
+
+func listDir(dir string) (map[string]entryState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]entryState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // Deleted since ReadDir
+		}
+		snapshot[entry.Name()] = entryState{
+			isDir:   entry.IsDir(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,113 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Clock tells the watcher the time. Tests can replace it to drive the
+// stability delay without waiting.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// snapshot summarizes a file or a folder tree, two equal snapshots mean
+// nothing in it changed in between
+type snapshot struct {
+	files   int
+	size    int64
+	latest  time.Time
+	partial bool // A download client's temporary file is still there
+}
+
+func takeSnapshot(path string) (snapshot, error) {
+	var snap snapshot
+	err := filepath.WalkDir(path, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		snap.files++
+		snap.size += info.Size()
+		if info.ModTime().After(snap.latest) {
+			snap.latest = info.ModTime()
+		}
+		if isTemporary(path) {
+			snap.partial = true
+		}
+		return nil
+	})
+	return snap, err
+}
+
+// stabilityTracker remembers the last snapshot of every due key. Instead of
+// stat, sleep, stat, a key is stable when two consecutive ticks see the same
+// snapshot. Only used from processStability, so it needs no lock.
+type stabilityTracker struct {
+	last map[string]snapshot
+}
+
+func newStabilityTracker() *stabilityTracker {
+	return &stabilityTracker{last: make(map[string]snapshot)}
+}
+
+// observe records snap for key. changed means it differs from the previous
+// one, settled that it is the same. The first observation is neither.
+func (t *stabilityTracker) observe(key string, snap snapshot) (changed, settled bool) {
+	prev, seen := t.last[key]
+	t.last[key] = snap
+	if !seen {
+		return false, false
+	}
+	if prev != snap {
+		return true, false
+	}
+	return false, true
+}
+
+// retain drops the snapshots of keys that are no longer due: a key that had
+// its delay restarted by an event starts over with a fresh first look.
+func (t *stabilityTracker) retain(due []string) {
+	keep := make(map[string]bool, len(due))
+	for _, key := range due {
+		keep[key] = true
+	}
+	for key := range t.last {
+		if !keep[key] {
+			delete(t.last, key)
+		}
+	}
+}
+
+func (t *stabilityTracker) forget(key string) {
+	delete(t.last, key)
+}
+
+// writersSet holds the paths some process has open for writing
+type writersSet map[string]struct{}
+
+// holds reports whether key, or anything inside it if it is a folder, is open for writing
+func (w writersSet) holds(key string) bool {
+	if _, ok := w[key]; ok {
+		return true
+	}
+	prefix := key + string(filepath.Separator)
+	for path := range w {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testDelay = 10 * time.Second
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// pendingFile sets up a watcher, without starting it, with one file pending
+// since the fake clock's current time.
+func pendingFile(t *testing.T) (*FolderWatcher, *fakeClock, string) {
+	t.Helper()
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	fw := &FolderWatcher{
+		Config:      WatcherConfig{Path: dir, StabilityDelay: testDelay},
+		StableFiles: make(chan Group, 1),
+		pending:     newPendingSet(0, "", clock),
+		stability:   newStabilityTracker(),
+	}
+	path := filepath.Join(dir, "Movie.2019.mkv")
+	writeFile(t, path)
+	fw.pending.add(context.Background(), path, true)
+	return fw, clock, path
+}
+
+// tick runs one check and returns the files handed over by it
+func tick(t *testing.T, fw *FolderWatcher) []string {
+	t.Helper()
+	if !fw.checkPending(context.Background()) {
+		t.Fatal("checkPending gave up")
+	}
+	select {
+	case group := <-fw.StableFiles:
+		return group.Files
+	default:
+		return nil
+	}
+}
+
+func TestCheckPendingWaitsForDelay(t *testing.T) {
+	fw, clock, _ := pendingFile(t)
+	clock.advance(testDelay - time.Second)
+	if files := tick(t, fw); files != nil {
+		t.Errorf("handed over %v before the delay", files)
+	}
+}
+
+func TestCheckPendingFirstLookNotSettled(t *testing.T) {
+	fw, clock, path := pendingFile(t)
+	clock.advance(testDelay)
+	if files := tick(t, fw); files != nil {
+		t.Fatalf("handed over %v on the first look", files)
+	}
+	if files := tick(t, fw); !slices.Equal(files, []string{path}) {
+		t.Errorf("got %v on the second look, want %s", files, path)
+	}
+}
+
+func TestCheckPendingChangeRestartsDelay(t *testing.T) {
+	changes := map[string]func(t *testing.T, path string){
+		"size": func(t *testing.T, path string) {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := f.WriteString("more"); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"mtime": func(t *testing.T, path string) {
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(path, later, later); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			fw, clock, path := pendingFile(t)
+			clock.advance(testDelay)
+			tick(t, fw) // First look
+
+			change(t, path)
+			if files := tick(t, fw); files != nil {
+				t.Fatalf("handed over %v while it changed", files)
+			}
+
+			// The delay starts over, then it needs two looks again
+			clock.advance(testDelay - time.Second)
+			if files := tick(t, fw); files != nil {
+				t.Fatalf("handed over %v before the restarted delay", files)
+			}
+			clock.advance(time.Second)
+			if files := tick(t, fw); files != nil {
+				t.Fatalf("handed over %v on the first look after the change", files)
+			}
+			if files := tick(t, fw); !slices.Equal(files, []string{path}) {
+				t.Errorf("got %v, want %s", files, path)
+			}
+		})
+	}
+}
+
+func TestReadyBlockedByWriter(t *testing.T) {
+	fw, clock, path := pendingFile(t)
+	clock.advance(testDelay)
+	tick(t, fw) // First look
+
+	writers := writersSet{path: {}}
+	if _, ready := fw.ready(path, writers); ready {
+		t.Fatal("ready while open for writing")
+	}
+	if files := tick(t, fw); files != nil {
+		t.Fatalf("handed over %v, the delay was not restarted", files)
+	}
+
+	// Closed by the writer, it goes through once the delay is over again
+	clock.advance(testDelay)
+	tick(t, fw)
+	if files := tick(t, fw); !slices.Equal(files, []string{path}) {
+		t.Errorf("got %v, want %s", files, path)
+	}
+}
+
+func TestWritersSetHoldsFolder(t *testing.T) {
+	writers := writersSet{filepath.Join("downloads", "Show", "e01.mkv"): {}}
+	if !writers.holds(filepath.Join("downloads", "Show")) {
+		t.Error("a folder with a file open for writing is not held")
+	}
+	if writers.holds(filepath.Join("downloads", "Sho")) {
+		t.Error("a folder sharing a prefix is held")
+	}
+}
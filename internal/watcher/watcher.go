@@ -1,12 +1,15 @@
 package watcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -16,36 +19,66 @@ type WatcherConfig struct {
 	Path           string
 	StabilityDelay time.Duration
 	Recursive      bool
+	// How often the whole folder is scanned again, in case an event was missed. 0 disables it
+	RescanInterval time.Duration
+	// How changes are noticed, fsnotify unless the folder is on a network/FUSE mount
+	Backend BackendKind
+	// How often the Poll backend lists the folder
+	PollInterval time.Duration
+	// Which files are reported at all, from patterns.toml
+	Filter Filter
+	// Most files waiting to become stable at once, adding more blocks. Defaults to 10000
+	MaxPending int
+	// Where the pending files are saved to survive a restart, optional
+	StateFile string
+	// Treat every top-level folder as one download that is ready only when all
+	// of it is stable, e.g. a season pack, instead of file by file
+	GroupByFolder bool
+	// Also wait while a process has the file open for writing, via /proc (Linux only)
+	CheckWriters bool
+	// Time source for the stability delay, the real clock if nil
+	Clock Clock
+	// Skip tells scans which files were already handled and must not be queued again
+	Skip func(path string) bool
 }
 
 type FolderWatcher struct {
 	Config      WatcherConfig
-	StableFiles chan string
-	watcher     *fsnotify.Watcher
-	stopCh      chan bool
-	pendingCh   chan string
-	done        chan bool
+	StableFiles chan Group
+	watcher     Backend
+	cancel      context.CancelFunc
+	pending     *pendingSet
+	stability   *stabilityTracker
+	rescanCh    chan struct{} // Asks scanLoop for a scan now, e.g. after events were lost
+	wg          sync.WaitGroup
 }
 
 func NewWatcher(config WatcherConfig) (*FolderWatcher, error) {
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := NewBackend(config.Backend, config.PollInterval)
 	if err != nil {
 		return nil, fmt.Errorf("error creating a watcher: %v", err)
 	}
 
+	clock := config.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
 	fw := &FolderWatcher{
 		Config:      config,
-		StableFiles: make(chan string),
+		StableFiles: make(chan Group),
 		watcher:     watcher,
-		stopCh:      make(chan bool),
-		pendingCh:   make(chan string, 100),
-		done:        make(chan bool),
+		pending:     newPendingSet(config.MaxPending, config.StateFile, clock),
+		stability:   newStabilityTracker(),
+		rescanCh:    make(chan struct{}, 1),
 	}
 
 	return fw, nil
 }
 
-func (fw *FolderWatcher) Start() error {
+// Start begins watching. The watcher stops, and StableFiles is closed, when
+// ctx is cancelled or Stop is called.
+func (fw *FolderWatcher) Start(ctx context.Context) error {
 	err := fw.watcher.Add(fw.Config.Path)
 	if err != nil {
 		return fmt.Errorf("error adding the path %s: %v", fw.Config.Path, err)
@@ -63,122 +96,305 @@ func (fw *FolderWatcher) Start() error {
 		})
 	}
 
-	go fw.watchFiles()
-	go fw.processStability()
+	if err := fw.pending.load(); err != nil {
+		log.Printf("Could not restore pending files: %v", err)
+	}
+
+	ctx, fw.cancel = context.WithCancel(ctx)
+
+	fw.wg.Add(3)
+	go fw.watchFiles(ctx)
+	go fw.processStability(ctx)
+	go fw.scanLoop(ctx)
 
-	log.Printf("Watcher iniciado para: %s", fw.Config.Path)
+	// Release the inotify handles once all goroutines are gone, however they were stopped
+	go func() {
+		fw.wg.Wait()
+		fw.watcher.Close()
+		log.Println("Watcher stopped")
+	}()
+
+	log.Printf("Watcher iniciado para: %s (%s)", fw.Config.Path, fw.Config.Backend)
 	return nil
 }
 
+// Stop cancels the watcher and waits for its goroutines to exit.
 func (fw *FolderWatcher) Stop() {
-	close(fw.stopCh)
-	<-fw.done
-	fw.watcher.Close()
-	log.Println("Watcher stopped")
+	if fw.cancel != nil {
+		fw.cancel()
+	}
+	fw.wg.Wait()
 }
 
-func (fw *FolderWatcher) watchFiles() {
-	defer close(fw.done)
+func (fw *FolderWatcher) watchFiles(ctx context.Context) {
+	defer fw.wg.Done()
 
 	for {
 		select {
-		case <-fw.stopCh:
+		case <-ctx.Done():
 			return
 
-		case event, ok := <-fw.watcher.Events:
+		case event, ok := <-fw.watcher.Events():
 			if !ok {
 				return
 			}
 
-			if event.Op&fsnotify.Create == fsnotify.Create ||
-				event.Op&fsnotify.Write == fsnotify.Write {
-
-				if info, err := os.Stat(event.Name); err == nil && info.IsDir() && fw.Config.Recursive {
-					fw.watcher.Add(event.Name)
+			switch {
+			case event.Has(fsnotify.Create):
+				// Also what a file or directory moved into the folder looks like,
+				// e.g. from a torrent client's incomplete dir or a .part renamed to .mkv
+				info, err := os.Stat(event.Name)
+				if err != nil {
 					continue
 				}
-
-				if fw.shouldProcessFile(event.Name) {
-					select {
-					case fw.pendingCh <- event.Name:
-					default:
-						log.Printf("Pending files channel full, ignoring: %s", event.Name)
+				if info.IsDir() {
+					if fw.Config.Recursive {
+						// Nothing else will announce the files already inside it
+						fw.queueTree(ctx, event.Name)
 					}
+					continue
 				}
+				fw.queueEvent(ctx, event.Name)
+
+			case event.Has(fsnotify.Write):
+				fw.queueEvent(ctx, event.Name)
+
+			case event.Has(fsnotify.Rename), event.Has(fsnotify.Remove):
+				// The old name is gone. If it was a directory its watch is stale now;
+				// a file that was pending is dropped by processStability once it can't stat it.
+				fw.watcher.Remove(event.Name)
 			}
 
-		case err, ok := <-fw.watcher.Errors:
+		case err, ok := <-fw.watcher.Errors():
 			if !ok {
 				return
 			}
 			log.Printf("Watcher error: %v", err)
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// The kernel dropped events, only a scan can find those files now
+				fw.requestScan()
+			}
+		}
+	}
+}
+
+// scanLoop queues the files that were already in the folder when the watcher
+// started (e.g. they arrived while the daemon was down) and, if configured,
+// rescans periodically as a safety net for missed events.
+func (fw *FolderWatcher) scanLoop(ctx context.Context) {
+	defer fw.wg.Done()
+
+	fw.scan(ctx)
+
+	// Without an interval the ticker never fires, scans only happen on request
+	var tick <-chan time.Time
+	if fw.Config.RescanInterval > 0 {
+		ticker := time.NewTicker(fw.Config.RescanInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			fw.scan(ctx)
+		case <-fw.rescanCh:
+			fw.scan(ctx)
+		}
+	}
+}
+
+func (fw *FolderWatcher) requestScan() {
+	select {
+	case fw.rescanCh <- struct{}{}:
+	default: // One is already requested
+	}
+}
+
+// scan queues every eligible file through the same stability check as events do
+func (fw *FolderWatcher) scan(ctx context.Context) {
+	if queued := fw.queueTree(ctx, fw.Config.Path); queued > 0 {
+		log.Printf("Scan of %s queued %d existing files", fw.Config.Path, queued)
+	}
+}
+
+// queueTree walks root, registering every directory with the watcher when
+// recursive, and queues the eligible files it finds. Returns how many were queued.
+func (fw *FolderWatcher) queueTree(ctx context.Context, root string) int {
+	queued := 0
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if path == fw.Config.Path {
+				return nil
+			}
+			if !fw.Config.Recursive {
+				return filepath.SkipDir
+			}
+			if fw.Config.Filter.IgnoreHidden && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if err := fw.watcher.Add(path); err != nil {
+				log.Printf("Could not watch %s: %v", path, err)
+			}
+			return nil
+		}
+		if !fw.shouldProcessFile(path) || (fw.Config.Skip != nil && fw.Config.Skip(path)) {
+			return nil
 		}
+
+		// Keeps the delay of a file that is already pending
+		if !fw.pending.add(ctx, fw.groupKey(path), false) {
+			return filepath.SkipAll
+		}
+		queued++
+		return nil
+	})
+	return queued
+}
+
+// queueEvent adds a file that just changed, restarting its delay. It blocks
+// while the pending set is full.
+func (fw *FolderWatcher) queueEvent(ctx context.Context, path string) {
+	key := fw.groupKey(path)
+	if key == path && !fw.shouldProcessFile(path) {
+		return
 	}
+	// Inside a grouped folder any change counts, a subtitle or a .part file
+	// still being written means the download isn't done
+	fw.pending.add(ctx, key, true)
 }
 
 // Needed only to make sure a file e.g. download is not still changing
-func (fw *FolderWatcher) processStability() {
+func (fw *FolderWatcher) processStability(ctx context.Context) {
+	defer fw.wg.Done()
 	defer close(fw.StableFiles)
+	defer func() {
+		if err := fw.pending.save(); err != nil {
+			log.Printf("%v", err)
+		}
+	}()
 
-	pending := make(map[string]time.Time)
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-fw.stopCh:
+		case <-ctx.Done():
 			return
 
-		case filePath := <-fw.pendingCh:
-			pending[filePath] = time.Now()
-
 		case <-ticker.C:
-			now := time.Now()
-			for filePath, addTime := range pending {
-				if now.Sub(addTime) >= fw.Config.StabilityDelay {
-					if fw.isFileStable(filePath) {
-						//Signal that a file is stable for processing
-						fw.StableFiles <- filePath
-
-						delete(pending, filePath)
-					} else {
-						// Reset the time if the file is still changing
-						pending[filePath] = now
-					}
-				}
+			if !fw.checkPending(ctx) {
+				return
+			}
+			if err := fw.pending.save(); err != nil {
+				log.Printf("%v", err)
 			}
 		}
 	}
 }
 
-func (fw *FolderWatcher) shouldProcessFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	tempExtensions := []string{
-		".tmp", ".part", ".crdownload", ".download", ".partial",
-		".!qb", ".!ut", // BitTorrent
-		".opdownload", // Opera
-		".wkdownload", // Firefox temporal
-		".filepart",   // Firefox
-		".bc!",        // BitComet
-		".dltemp",     // Download temporal
+// checkPending is one tick of processStability: it looks at every key whose
+// delay is over and hands over the ones that are ready. It never sleeps, a key
+// that needs a second look simply waits for the next tick. Returns false if ctx
+// ended while handing a group over.
+func (fw *FolderWatcher) checkPending(ctx context.Context) bool {
+	due := fw.pending.due(fw.Config.StabilityDelay)
+	fw.stability.retain(due)
+
+	var writers writersSet
+	if fw.Config.CheckWriters && len(due) > 0 {
+		writers = openForWriting()
 	}
 
-	return !slices.Contains(tempExtensions, ext)
+	for _, key := range due {
+		group, ready := fw.ready(key, writers)
+		if !ready {
+			continue
+		}
+		if len(group.Files) > 0 {
+			//Signal that a file is stable for processing
+			select {
+			case fw.StableFiles <- group:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		fw.pending.remove(key)
+		fw.stability.forget(key)
+	}
+	return true
 }
 
-func (fw *FolderWatcher) isFileStable(filePath string) bool {
-	info1, err := os.Stat(filePath)
+// ready checks a due pending key. It returns ready=true once the key can leave
+// the pending set, with the files to hand over (none if nothing is left to do).
+// A key that is still changing gets its delay restarted.
+func (fw *FolderWatcher) ready(key string, writers writersSet) (Group, bool) {
+	info, err := os.Stat(key)
+	if os.IsNotExist(err) {
+		// Renamed, moved away or deleted while waiting
+		return Group{}, true
+	}
 	if err != nil {
-		return false
+		return Group{}, false
 	}
 
-	time.Sleep(500 * time.Millisecond)
-
-	info2, err := os.Stat(filePath)
+	snap, err := takeSnapshot(key)
 	if err != nil {
+		return Group{}, false
+	}
+	changed, settled := fw.stability.observe(key, snap)
+	if changed || snap.partial || writers.holds(key) {
+		// Reset the time if the file is still changing
+		fw.pending.touch(key)
+		return Group{}, false
+	}
+	if !settled {
+		return Group{}, false // First look, compare on the next tick
+	}
+
+	if info.IsDir() {
+		group := fw.collectGroup(key)
+		log.Printf("Folder %s is complete, %d files ready", key, len(group.Files))
+		return group, true
+	}
+	// Only meaningful now that the file stopped growing
+	if !fw.Config.Filter.AllowsSize(info.Size()) {
+		log.Printf("File is smaller than the minimum size, ignoring: %s", key)
+		return Group{}, true
+	}
+	return Group{Files: []string{key}}, true
+}
+
+var tempExtensions = []string{
+	".tmp", ".part", ".crdownload", ".download", ".partial",
+	".!qb", ".!ut", // BitTorrent
+	".opdownload", // Opera
+	".wkdownload", // Firefox temporal
+	".filepart",   // Firefox
+	".bc!",        // BitComet
+	".dltemp",     // Download temporal
+}
+
+// isTemporary reports whether a download client is still writing the file
+func isTemporary(filePath string) bool {
+	return slices.Contains(tempExtensions, strings.ToLower(filepath.Ext(filePath)))
+}
+
+func (fw *FolderWatcher) shouldProcessFile(filePath string) bool {
+	if isTemporary(filePath) {
 		return false
 	}
 
-	// The file is stable if it hasn't changed its size and modification time
-	return info1.Size() == info2.Size() && info1.ModTime().Equal(info2.ModTime())
+	rel, err := filepath.Rel(fw.Config.Path, filePath)
+	if err != nil {
+		rel = filepath.Base(filePath)
+	}
+	return fw.Config.Filter.Allows(rel)
 }
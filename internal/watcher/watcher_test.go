@@ -0,0 +1,132 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var backends = []BackendKind{Fsnotify, Poll}
+
+// startWatcher watches a fresh temporary folder with the given backend. The
+// watcher is stopped when the test ends.
+func startWatcher(t *testing.T, backend BackendKind) (*FolderWatcher, string) {
+	t.Helper()
+	dir := t.TempDir()
+	fw, err := NewWatcher(WatcherConfig{
+		Path:         dir,
+		Recursive:    true,
+		Backend:      backend,
+		PollInterval: 50 * time.Millisecond,
+		Filter:       Filter{Extensions: []string{".mkv"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fw.Stop)
+	return fw, dir
+}
+
+// nextGroup waits for the watcher to hand over a group
+func nextGroup(t *testing.T, fw *FolderWatcher) Group {
+	t.Helper()
+	select {
+	case group := <-fw.StableFiles:
+		return group
+	case <-time.After(10 * time.Second):
+		t.Fatal("no stable file was handed over")
+		return Group{}
+	}
+}
+
+// noGroup fails if the watcher hands over anything within wait
+func noGroup(t *testing.T, fw *FolderWatcher, wait time.Duration) {
+	t.Helper()
+	select {
+	case group := <-fw.StableFiles:
+		t.Fatalf("unexpected group %v", group)
+	case <-time.After(wait):
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMovedInFile(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(string(backend), func(t *testing.T) {
+			fw, dir := startWatcher(t, backend)
+			src := filepath.Join(t.TempDir(), "Movie.2019.mkv")
+			writeFile(t, src)
+
+			dst := filepath.Join(dir, "Movie.2019.mkv")
+			if err := os.Rename(src, dst); err != nil {
+				t.Fatal(err)
+			}
+
+			if group := nextGroup(t, fw); !slices.Equal(group.Files, []string{dst}) {
+				t.Errorf("got %v, want %s", group.Files, dst)
+			}
+		})
+	}
+}
+
+func TestMovedInTree(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(string(backend), func(t *testing.T) {
+			fw, dir := startWatcher(t, backend)
+			src := filepath.Join(t.TempDir(), "Show")
+			writeFile(t, filepath.Join(src, "Season 1", "Show.S01E01.mkv"))
+
+			show := filepath.Join(dir, "Show")
+			if err := os.Rename(src, show); err != nil {
+				t.Fatal(err)
+			}
+
+			first := filepath.Join(show, "Season 1", "Show.S01E01.mkv")
+			if group := nextGroup(t, fw); !slices.Equal(group.Files, []string{first}) {
+				t.Fatalf("got %v, want %s", group.Files, first)
+			}
+
+			// Only seen if the nested folder was registered when the tree arrived
+			second := filepath.Join(show, "Season 1", "Show.S01E02.mkv")
+			writeFile(t, second)
+			if group := nextGroup(t, fw); !slices.Equal(group.Files, []string{second}) {
+				t.Errorf("got %v, want %s", group.Files, second)
+			}
+		})
+	}
+}
+
+func TestPartRenamed(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(string(backend), func(t *testing.T) {
+			fw, dir := startWatcher(t, backend)
+			part := filepath.Join(dir, "Movie.2019.mkv.part")
+			writeFile(t, part)
+			noGroup(t, fw, 2500*time.Millisecond)
+
+			final := filepath.Join(dir, "Movie.2019.mkv")
+			if err := os.Rename(part, final); err != nil {
+				t.Fatal(err)
+			}
+
+			if group := nextGroup(t, fw); !slices.Equal(group.Files, []string{final}) {
+				t.Errorf("got %v, want %s", group.Files, final)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+//go:build linux
+
+package watcher
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// openForWriting lists every file some process has open with O_WRONLY or
+// O_RDWR, reading /proc/<pid>/fd and fdinfo. Processes we aren't allowed
+// to inspect are skipped, so run as the download client's user for this to be useful.
+func openForWriting() writersSet {
+	writers := make(writersSet)
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return writers
+	}
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "/") {
+				continue // Sockets, pipes and the like
+			}
+			if writable(filepath.Join("/proc", proc.Name(), "fdinfo", fd.Name())) {
+				writers[target] = struct{}{}
+			}
+		}
+	}
+	return writers
+}
+
+// writable reads the open flags from an fdinfo file, they are in octal
+func writable(fdinfo string) bool {
+	f, err := os.Open(fdinfo)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return false
+		}
+		return flags&uint64(os.O_WRONLY|os.O_RDWR) != 0
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package watcher
+
+// openForWriting needs /proc, elsewhere only size and mtime are checked
+func openForWriting() writersSet {
+	return nil
+}